@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/dustin/go-humanize"
+	"github.com/iml1s/antigravity-cleaner/internal/sizecache"
+	"github.com/spf13/cobra"
+)
+
+// newCacheCmd builds "agc cache" and its subcommands
+func newCacheCmd() *cobra.Command {
+	cacheCmd := &cobra.Command{
+		Use:   "cache",
+		Short: "Manage the directory size cache",
+		Long: `agc remembers directory sizes between runs so unchanged trees are not walked
+again. A cached directory is reused only while its modification time and inode
+are unchanged. Use --no-cache to bypass it for one run.`,
+	}
+
+	clearCmd := &cobra.Command{
+		Use:   "clear",
+		Short: "Delete the directory size cache",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := sizecache.DefaultPath()
+			var size int64
+			if info, err := os.Stat(path); err == nil {
+				size = info.Size()
+			}
+			if err := sizecache.Clear(path); err != nil {
+				return err
+			}
+			fmt.Printf("✓ Cleared size cache %s (%s)\n", path, humanize.Bytes(uint64(size)))
+			return nil
+		},
+	}
+
+	pathCmd := &cobra.Command{
+		Use:   "path",
+		Short: "Print the location of the cache file",
+		Run: func(cmd *cobra.Command, args []string) {
+			fmt.Println(sizecache.DefaultPath())
+		},
+	}
+
+	cacheCmd.AddCommand(clearCmd, pathCmd)
+	return cacheCmd
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/iml1s/antigravity-cleaner/internal/config"
+	"github.com/iml1s/antigravity-cleaner/internal/workspaces"
+	"github.com/spf13/cobra"
+)
+
+// newConfigCmd builds "agc config" and its subcommands
+func newConfigCmd() *cobra.Command {
+	configCmd := &cobra.Command{
+		Use:   "config",
+		Short: "Manage the configuration file",
+		Long: `The configuration file sets custom paths, excluded paths, scan roots, size
+thresholds and default flag values. It is read from
+$XDG_CONFIG_HOME/agc/config.yaml unless --config names another file.`,
+	}
+
+	var force bool
+	initCmd := &cobra.Command{
+		Use:   "init",
+		Short: "Write a commented starter configuration file",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if configPath == "" {
+				return errors.New("no configuration directory; set $XDG_CONFIG_HOME or use --config")
+			}
+			if _, err := os.Stat(configPath); err == nil && !force {
+				return fmt.Errorf("%s already exists (use --force to overwrite)", configPath)
+			}
+			if err := os.MkdirAll(filepath.Dir(configPath), 0o755); err != nil {
+				return err
+			}
+			if err := os.WriteFile(configPath, config.Template, 0o644); err != nil {
+				return err
+			}
+			fmt.Printf("✓ Wrote %s\n", configPath)
+			return nil
+		},
+	}
+	initCmd.Flags().BoolVar(&force, "force", false, "Overwrite an existing file")
+
+	showCmd := &cobra.Command{
+		Use:   "show",
+		Short: "Print the configuration in effect",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if cfgErr != nil {
+				return cfgErr
+			}
+			if cfg.Path == "" {
+				fmt.Printf("# %s does not exist; using built-in defaults\n", configPath)
+			} else {
+				fmt.Printf("# %s\n", cfg.Path)
+			}
+			data, err := cfg.Marshal()
+			if err != nil {
+				return err
+			}
+			fmt.Print(string(data))
+			return nil
+		},
+	}
+
+	pathCmd := &cobra.Command{
+		Use:   "path",
+		Short: "Print the location of the configuration file",
+		Run: func(cmd *cobra.Command, args []string) {
+			fmt.Println(configPath)
+		},
+	}
+
+	validateCmd := &cobra.Command{
+		Use:   "validate [file]",
+		Short: "Check a configuration file for errors",
+		Long:  "Validate the given configuration file, or the one in use.",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := configPath
+			if len(args) > 0 {
+				path = args[0]
+			}
+			if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+				return fmt.Errorf("%s does not exist (run 'agc config init' to create it)", path)
+			}
+			if _, err := config.Load(path); err != nil {
+				fmt.Printf("❌ %v\n", err)
+				return errors.New("config validation failed")
+			}
+			fmt.Printf("✓ %s\n", path)
+			return nil
+		},
+	}
+
+	rootsCmd := &cobra.Command{
+		Use:   "roots",
+		Short: "List the directories searched for projects",
+		Long: `List the configured scan roots and the workspaces recently opened in
+Antigravity, VS Code and Cursor, which are searched as well.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if cfgErr != nil {
+				return cfgErr
+			}
+			fmt.Println("📁 Scan roots:")
+			if roots := cfg.Roots(); len(roots) > 0 {
+				for _, root := range roots {
+					fmt.Printf("   %s\n", root)
+				}
+			} else {
+				home, _ := os.UserHomeDir()
+				fmt.Printf("   %s (default)\n", filepath.Join(home, "Documents"))
+			}
+
+			fmt.Println()
+			if !cfg.Discover() {
+				fmt.Println("🔎 Workspace discovery is off (discover_workspaces: false)")
+				return nil
+			}
+			found := workspaces.Discover()
+			if len(found) == 0 {
+				fmt.Println("🔎 No recent Antigravity, VS Code or Cursor workspaces found")
+				return nil
+			}
+			fmt.Println("🔎 Recent workspaces:")
+			for _, ws := range found {
+				fmt.Printf("   %s (%s)\n", ws.Path, strings.Join(ws.Editors, ", "))
+			}
+			return nil
+		},
+	}
+
+	configCmd.AddCommand(initCmd, showCmd, pathCmd, validateCmd, rootsCmd)
+	return configCmd
+}
+
+// isConfigCmd reports whether cmd is "agc config" or one of its subcommands
+func isConfigCmd(cmd *cobra.Command) bool {
+	for c := cmd; c.HasParent(); c = c.Parent() {
+		if c.Parent() == c.Root() {
+			return c.Name() == "config"
+		}
+	}
+	return false
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/iml1s/antigravity-cleaner/internal/cleaner"
+	"github.com/iml1s/antigravity-cleaner/internal/history"
+	"github.com/iml1s/antigravity-cleaner/internal/scanner"
+	"github.com/iml1s/antigravity-cleaner/internal/ui"
+	"github.com/iml1s/antigravity-cleaner/internal/units"
+	"github.com/spf13/cobra"
+)
+
+// historyIcons marks each outcome in the history listing
+var historyIcons = map[string]string{
+	string(cleaner.Removed):     "✓",
+	string(cleaner.Quarantined): "📦",
+	string(cleaner.Trashed):     "🗑",
+	string(cleaner.Failed):      "❌",
+	history.Restored:            "↩",
+	history.Purged:              "🔥",
+}
+
+// historyPeriods are the accepted --by values
+var historyPeriods = []string{"day", "week", "month"}
+
+// newHistoryCmd builds "agc history"
+func newHistoryCmd() *cobra.Command {
+	var since, until, by string
+	var categories []string
+	var totalsOnly bool
+
+	cmd := &cobra.Command{
+		Use:   "history",
+		Short: "Show what agc cleaned, and the space freed over time",
+		Long: `List the journal of everything agc removed, quarantined, trashed, restored
+or purged, with totals per day, week or month. The journal is kept in
+$XDG_STATE_HOME/agc/history.jsonl, one JSON record per line.
+
+--since and --until take a date (2026-10-13) or an age (7d, 2w); a date
+given to --until includes that whole day.`,
+		Example: `  agc history --since 7d
+  agc history --since 2026-10-13 --until 2026-10-13
+  agc history --category flutter --totals --by month`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			now := time.Now()
+			var from, to time.Time
+			var err error
+			if since != "" {
+				if from, err = parseWhen(since, now, false); err != nil {
+					return fmt.Errorf("invalid --since: %w", err)
+				}
+			}
+			if until != "" {
+				if to, err = parseWhen(until, now, true); err != nil {
+					return fmt.Errorf("invalid --until: %w", err)
+				}
+			}
+			if !contains(historyPeriods, by) {
+				return fmt.Errorf("invalid --by %q (use one of %s)", by, strings.Join(historyPeriods, ", "))
+			}
+			// Records hold the category's display name; accept provider
+			// IDs such as "vscode" for it too
+			for i, c := range categories {
+				if p := scanner.Lookup(c); p != nil {
+					categories[i] = p.Name()
+				}
+			}
+
+			path := history.DefaultPath()
+			records, bad, err := history.Read(path)
+			if err != nil {
+				return err
+			}
+			if bad > 0 {
+				fmt.Printf("⚠️  Ignored %s in %s that could not be read\n", ui.Pluralize(bad, "line"), path)
+			}
+
+			var shown []history.Record
+			for _, rec := range records {
+				if !from.IsZero() && rec.Time.Before(from) {
+					continue
+				}
+				if !to.IsZero() && !rec.Time.Before(to) {
+					continue
+				}
+				if len(categories) > 0 && !containsFold(categories, rec.Category) {
+					continue
+				}
+				shown = append(shown, rec)
+			}
+			if len(shown) == 0 {
+				fmt.Println("No history recorded for these filters.")
+				return nil
+			}
+			// Runs append in the order they finish, which a long run can put
+			// after a later one
+			sort.SliceStable(shown, func(i, j int) bool { return shown[i].Time.Before(shown[j].Time) })
+
+			if !totalsOnly {
+				for _, rec := range shown {
+					icon := historyIcons[rec.Outcome]
+					if icon == "" {
+						icon = "•"
+					}
+					fmt.Printf("%s  %s %-11s %9s  %-12s %s\n", rec.Time.Local().Format("2006-01-02 15:04"), icon, rec.Outcome,
+						humanize.Bytes(uint64(rec.Bytes)), rec.Category, rec.Path)
+					if rec.Error != "" {
+						fmt.Printf("                     %s\n", rec.Error)
+					}
+				}
+				fmt.Println()
+			}
+			displayHistoryTotals(shown, by)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&since, "since", "", "Only show records from this date or age on (e.g. 2026-10-01, 7d)")
+	cmd.Flags().StringVar(&until, "until", "", "Only show records up to this date or age (e.g. 2026-10-13, 1d)")
+	cmd.Flags().StringSliceVar(&categories, "category", nil, "Only show these categories, by name or ID (e.g. flutter,vscode)")
+	cmd.Flags().StringVar(&by, "by", "day", "Group the totals by "+strings.Join(historyPeriods, ", "))
+	cmd.Flags().BoolVar(&totalsOnly, "totals", false, "Only show the totals")
+	return cmd
+}
+
+// historyTotals sums the records of one period
+type historyTotals struct {
+	items       int
+	freed       int64
+	quarantined int64
+	trashed     int64
+	failed      int
+}
+
+func (t *historyTotals) add(rec history.Record) {
+	t.items++
+	t.freed += rec.Freed()
+	switch rec.Outcome {
+	case string(cleaner.Quarantined):
+		t.quarantined += rec.Bytes
+	case string(cleaner.Trashed):
+		t.trashed += rec.Bytes
+	case string(cleaner.Failed):
+		t.failed++
+	}
+}
+
+func (t historyTotals) String() string {
+	parts := []string{humanize.Bytes(uint64(t.freed)) + " freed"}
+	if t.quarantined > 0 {
+		parts = append(parts, humanize.Bytes(uint64(t.quarantined))+" quarantined")
+	}
+	if t.trashed > 0 {
+		parts = append(parts, humanize.Bytes(uint64(t.trashed))+" trashed")
+	}
+	if t.failed > 0 {
+		parts = append(parts, fmt.Sprintf("%d failed", t.failed))
+	}
+	return fmt.Sprintf("%-10s %s", ui.Pluralize(t.items, "item"), strings.Join(parts, ", "))
+}
+
+// displayHistoryTotals prints the records' totals per period, oldest
+// first, and overall
+func displayHistoryTotals(records []history.Record, by string) {
+	periods := make(map[string]*historyTotals)
+	var keys []string
+	var all historyTotals
+	for _, rec := range records {
+		key := periodOf(rec.Time.Local(), by)
+		t, ok := periods[key]
+		if !ok {
+			t = &historyTotals{}
+			periods[key] = t
+			keys = append(keys, key)
+		}
+		t.add(rec)
+		all.add(rec)
+	}
+	sort.Strings(keys)
+
+	fmt.Printf("📊 Totals by %s:\n", by)
+	for _, key := range keys {
+		fmt.Printf("  %-10s  %s\n", key, periods[key])
+	}
+	fmt.Printf("  %-10s  %s\n", "all", all)
+}
+
+// periodOf names the day, week or month t falls in. Weeks start on Monday
+// and are named by it.
+func periodOf(t time.Time, by string) string {
+	switch by {
+	case "week":
+		offset := (int(t.Weekday()) + 6) % 7
+		return t.AddDate(0, 0, -offset).Format("2006-01-02")
+	case "month":
+		return t.Format("2006-01")
+	}
+	return t.Format("2006-01-02")
+}
+
+// parseWhen reads a local date such as 2026-10-13, or an age such as 7d
+// counted back from now. With endOfDay, a date means the end of that day.
+func parseWhen(s string, now time.Time, endOfDay bool) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		if endOfDay {
+			t = t.AddDate(0, 0, 1)
+		}
+		return t, nil
+	}
+	d, err := units.ParseDuration(s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is neither a date (2026-10-13) nor an age (7d)", s)
+	}
+	return now.Add(-d), nil
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -1,18 +1,196 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
+	"github.com/dustin/go-humanize"
 	"github.com/iml1s/antigravity-cleaner/internal/cleaner"
+	"github.com/iml1s/antigravity-cleaner/internal/config"
+	"github.com/iml1s/antigravity-cleaner/internal/filter"
+	"github.com/iml1s/antigravity-cleaner/internal/history"
+	"github.com/iml1s/antigravity-cleaner/internal/profile"
+	"github.com/iml1s/antigravity-cleaner/internal/report"
+	"github.com/iml1s/antigravity-cleaner/internal/rules"
 	"github.com/iml1s/antigravity-cleaner/internal/scanner"
+	"github.com/iml1s/antigravity-cleaner/internal/sizecache"
 	"github.com/iml1s/antigravity-cleaner/internal/ui"
+	"github.com/iml1s/antigravity-cleaner/internal/units"
+	"github.com/iml1s/antigravity-cleaner/internal/workspaces"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 var version = "dev"
 
+// Exit codes. Cleaning commands distinguish how the run went; any other
+// error exits with exitError.
+const (
+	exitOK      = 0
+	exitError   = 1
+	exitPartial = 2
+	exitFailed  = 3
+	exitNothing = 4
+)
+
+// exitCode is set by commands that finish without an error
+var exitCode = exitOK
+
+var cleanExitCodes = map[cleaner.Status]int{
+	cleaner.Success:     exitOK,
+	cleaner.Partial:     exitPartial,
+	cleaner.AllFailed:   exitFailed,
+	cleaner.NothingToDo: exitNothing,
+}
+
+// Global flags shared by every scanning command
+var (
+	jobs    int
+	timeout time.Duration
+	noCache bool
+	verbose bool
+	minSize string
+	// olderThan is the parsed --older-than flag
+	olderThan     time.Duration
+	olderThanFlag string
+
+	// cfg is the user configuration, loaded from --config before the
+	// commands are built. cfgErr is reported by every command except
+	// "agc config", which has to work with a broken file.
+	configPath string
+	cfg        = &config.Config{}
+	cfgErr     error
+
+	// profileName is the --profile flag of scan and clean, and
+	// activeProfile the profile it names
+	profileName   string
+	activeProfile *profile.Profile
+
+	// outputFormat is the --output flag of scan and clean
+	outputFormat = "text"
+
+	// selectExpr is the --select flag of scan and clean, and selectFilter
+	// its compiled form
+	selectExpr   string
+	selectFilter *filter.Filter
+
+	// targetFreePercent is the --target-free-percent flag of clean; zero
+	// cleans regardless of free space
+	targetFreePercent float64
+
+	// strategy is the --strategy flag of clean and the provider commands,
+	// and permanent its --permanent shorthand for delete
+	strategy  string
+	permanent bool
+
+	// force is the --force flag of clean and the provider commands, which
+	// cleans items even while running processes use them
+	force bool
+)
+
+// runScan runs scan with a session built from the global flags, bounded by
+// --timeout and showing live progress when stderr is a terminal. onItem, if
+// set, is called for every item as soon as it's found.
+func runScan(ctx context.Context, onItem func(scanner.CleanableItem), scan func(context.Context, *scanner.Session) scanner.Result) scanner.Result {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	opts := scanner.Options{
+		Jobs:       jobs,
+		Thresholds: scanner.Thresholds{Categories: cfg.ThresholdBytes()},
+		OlderThan:  olderThan,
+		Roots:      cfg.Roots(),
+		Workspaces: discoveredWorkspaces(),
+		Exclude:    cfg.ExcludePatterns(),
+	}
+	if minSize != "" {
+		// Validated by the root command's PersistentPreRunE
+		n, _ := humanize.ParseBytes(minSize)
+		global := int64(n)
+		opts.Thresholds.Global = &global
+	}
+	if !noCache {
+		opts.Cache = sizecache.Open(sizecache.DefaultPath())
+	}
+	progress := ui.NewProgress()
+	if progress != nil || onItem != nil {
+		opts.Progress = func(ev scanner.Event) {
+			if progress != nil {
+				progress.Handle(ev)
+			}
+			if onItem != nil && ev.Kind == scanner.ItemFound {
+				onItem(*ev.Item)
+			}
+		}
+	}
+
+	result := scan(ctx, scanner.NewSession(opts))
+	if progress != nil {
+		progress.Stop()
+	}
+	if opts.Cache != nil {
+		if err := opts.Cache.Save(); err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  Could not save size cache: %v\n", err)
+		}
+	}
+	return result
+}
+
+// discoveredWorkspaces returns the recently opened editor workspaces, or
+// nil when the configuration turns discovery off
+func discoveredWorkspaces() []string {
+	if !cfg.Discover() {
+		return nil
+	}
+	var paths []string
+	for _, ws := range workspaces.Discover() {
+		paths = append(paths, ws.Path)
+	}
+	return paths
+}
+
+// checkScan decides whether an incomplete scan can still be acted on. A
+// timed-out scan is usable with a warning; an interrupted one is not.
+func checkScan(result scanner.Result) error {
+	switch {
+	case errors.Is(result.Err, context.Canceled):
+		return errors.New("scan interrupted")
+	case result.Err != nil:
+		fmt.Fprintf(os.Stderr, "⚠️  Scan timed out after %s; results are incomplete\n", timeout)
+	}
+	if n := result.ErrorCount(); n > 0 {
+		fmt.Fprintf(os.Stderr, "⚠️  %d paths could not be read; sizes may be incomplete (see 'agc scan --verbose')\n", n)
+	}
+	return nil
+}
+
 func main() {
+	configPath = configFlag(os.Args[1:])
+	if loaded, err := config.Load(configPath); err != nil {
+		cfgErr = err
+	} else {
+		cfg = loaded
+	}
+
+	// Ctrl+C cancels the running scan; a second Ctrl+C exits immediately
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
 	var rootCmd = &cobra.Command{
 		Use:   "agc",
 		Short: "Antigravity Cleaner - Clean up IDE caches and build artifacts",
@@ -26,135 +204,615 @@ Supports:
   - Android Studio (.gradle caches, AVD images)
   - VS Code and variants (CachedData, extensions)
   - iOS/Android Simulators (old runtimes)`,
-		Version: version,
+		Version:       version,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if cfgErr != nil && !isConfigCmd(cmd) {
+				return cfgErr
+			}
+
+			// Flags given on the command line win over the profile, and
+			// the profile over the configured defaults
+			if err := applyPermanent(cmd); err != nil {
+				return err
+			}
+			if profileName != "" {
+				profiles := cfg.AllProfiles()
+				p, ok := profiles[profileName]
+				if !ok {
+					return fmt.Errorf("unknown profile %q (available: %s)", profileName, strings.Join(profile.Names(profiles), ", "))
+				}
+				if err := setUnchangedFlags(cmd, p.Flags()); err != nil {
+					return fmt.Errorf("profile %s: %w", p.Name, err)
+				}
+				activeProfile = &p
+			}
+			if err := setUnchangedFlags(cmd, cfg.Defaults.Flags()); err != nil {
+				return fmt.Errorf("%s: defaults: %w", configPath, err)
+			}
+
+			if minSize != "" {
+				if _, err := humanize.ParseBytes(minSize); err != nil {
+					return fmt.Errorf("invalid --min-size %q", minSize)
+				}
+			}
+			if err := compileSelect(); err != nil {
+				return err
+			}
+			if err := checkStrategy(cmd); err != nil {
+				return err
+			}
+			if !contains(report.Formats, outputFormat) {
+				return fmt.Errorf("invalid --output %q (use one of %s)", outputFormat, strings.Join(report.Formats, ", "))
+			}
+			if olderThanFlag != "" {
+				d, err := units.ParseDuration(olderThanFlag)
+				if err != nil {
+					return fmt.Errorf("invalid --older-than %q (use e.g. 12h, 30d, 6mo, or 0 for no limit)", olderThanFlag)
+				}
+				olderThan = d
+			}
+			return nil
+		},
 	}
 
+	rootCmd.PersistentFlags().StringVar(&configPath, "config", config.DefaultPath(), "Configuration file")
+	rootCmd.PersistentFlags().IntVarP(&jobs, "jobs", "j", 0, "Number of directories to scan in parallel (default: number of CPUs)")
+	rootCmd.PersistentFlags().BoolVar(&verbose, "verbose", false, "Show every path that could not be read")
+	rootCmd.PersistentFlags().StringVar(&minSize, "min-size", "", "Only show items larger than this, overriding all thresholds (e.g. 500MB, 0 for everything)")
+	rootCmd.PersistentFlags().StringVar(&olderThanFlag, "older-than", "", "Only show items not modified or accessed for this long (e.g. 30d, 2w, 6mo)")
+	rootCmd.PersistentFlags().BoolVar(&noCache, "no-cache", false, "Measure every directory from scratch instead of reusing cached sizes")
+	rootCmd.PersistentFlags().DurationVar(&timeout, "timeout", 0, "Stop scanning after this long and show partial results (e.g. 30s, 5m)")
+
 	// Scan command
+	var scanOnly, scanSkip []string
 	var scanCmd = &cobra.Command{
 		Use:   "scan",
 		Short: "Scan for cleanable items",
 		Long:  "Scan your system for IDE caches, build artifacts, and other cleanable items.",
-		Run: func(cmd *cobra.Command, args []string) {
-			results := scanner.ScanAll()
-			ui.DisplayScanResults(results)
+		RunE: func(cmd *cobra.Command, args []string) error {
+			providers, err := scanner.Select(scanOnly, scanSkip)
+			if err != nil {
+				return err
+			}
+
+			// NDJSON streams each item as soon as it's found
+			var stream *report.Stream
+			var onItem func(scanner.CleanableItem)
+			if outputFormat == "ndjson" {
+				stream = report.NewStream(os.Stdout)
+				now := time.Now()
+				onItem = func(item scanner.CleanableItem) {
+					if keepItem(item, now) {
+						stream.Item(item)
+					}
+				}
+			}
+
+			result := runScan(cmd.Context(), onItem, func(ctx context.Context, s *scanner.Session) scanner.Result {
+				return scanner.ScanProviders(ctx, s, providers)
+			})
+			applyFilters(&result)
+			switch {
+			case stream != nil:
+				return stream.Finish(report.NewDocument("scan", result, result.Items))
+			case outputFormat != "text":
+				return report.Write(os.Stdout, outputFormat, report.NewDocument("scan", result, result.Items))
+			}
+			ui.DisplayScanResults(result, verbose)
+			return nil
 		},
 	}
+	addCategoryFlags(scanCmd, &scanOnly, &scanSkip)
+	addProfileFlag(scanCmd)
+	addSelectFlag(scanCmd)
+	addOutputFlag(scanCmd)
 
 	// Clean command
 	var cleanAll bool
 	var cleanDryRun bool
+	var cleanFree string
+	var includeCaution, includeWarning bool
+	var cleanOnly, cleanSkip []string
 	var cleanCmd = &cobra.Command{
 		Use:   "clean",
 		Short: "Clean up caches and build artifacts",
 		Long:  "Interactively select and clean IDE caches, build artifacts, and temporary files.",
-		Run: func(cmd *cobra.Command, args []string) {
-			results := scanner.ScanAll()
-			if len(results) == 0 {
-				fmt.Println("No cleanable items found.")
-				return
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// The interactive selection would garble machine-readable output
+			if outputFormat != "text" && !cleanAll {
+				return fmt.Errorf("--output %s requires --all", outputFormat)
+			}
+			var target int64
+			if cleanFree != "" {
+				n, err := humanize.ParseBytes(cleanFree)
+				if err != nil || n == 0 {
+					return fmt.Errorf("invalid --free %q (use e.g. 20GB)", cleanFree)
+				}
+				target = int64(n)
+			}
+			if cmd.Flags().Changed("target-free-percent") && (targetFreePercent <= 0 || targetFreePercent > 100) {
+				return fmt.Errorf("--target-free-percent must be above 0 and at most 100, got %g", targetFreePercent)
+			}
+			if err := freeingStrategy(cmd, target > 0 || targetFreePercent > 0); err != nil {
+				return err
+			}
+			providers, err := scanner.Select(cleanOnly, cleanSkip)
+			if err != nil {
+				return err
+			}
+			result := runScan(cmd.Context(), nil, func(ctx context.Context, s *scanner.Session) scanner.Result {
+				return scanner.ScanProviders(ctx, s, providers)
+			})
+			if err := checkScan(result); err != nil {
+				return err
+			}
+			applyFilters(&result)
+			var held int64
+			level := unattendedLevel(includeCaution, includeWarning)
+			if cleanAll {
+				result.Items, held = holdBackRisky(result.Items, level)
+			}
+			results := result.Items
+
+			if target > 0 {
+				return cleanToFree(result, target, held, level, cleanAll, cleanDryRun)
 			}
 
+			emptyMsg := "No cleanable items found."
 			var toClean []scanner.CleanableItem
-			if cleanAll {
+			switch {
+			case cleanAll:
 				toClean = results
-			} else {
+			case len(results) > 0:
 				toClean = ui.SelectItems(results)
-			}
-
-			if len(toClean) == 0 {
-				fmt.Println("No items selected for cleaning.")
-				return
+				emptyMsg = "No items selected for cleaning."
+				if !cleanDryRun {
+					if !ui.ConfirmRisky(toClean) {
+						fmt.Println("Nothing cleaned.")
+						exitCode = exitNothing
+						return nil
+					}
+					confirmInUse(toClean)
+				}
 			}
 
 			if cleanDryRun {
+				toClean = applyFreeTarget(toClean)
+				if len(toClean) == 0 {
+					exitCode = exitNothing
+				}
+				if outputFormat != "text" {
+					return report.Write(os.Stdout, outputFormat, report.NewDocument("dry-run", result, toClean))
+				}
+				if len(toClean) == 0 {
+					fmt.Println(emptyMsg)
+					return nil
+				}
 				ui.DisplayDryRun(toClean)
-				return
+				return nil
 			}
-
-			cleaner.CleanItems(toClean)
+			return runClean(toClean, emptyMsg)
 		},
 	}
 	cleanCmd.Flags().BoolVarP(&cleanAll, "all", "a", false, "Clean all items without prompting")
 	cleanCmd.Flags().BoolVarP(&cleanDryRun, "dry-run", "n", false, "Show what would be cleaned without actually cleaning")
+	cleanCmd.Flags().StringVar(&cleanFree, "free", "", "Clean the least risky, oldest and largest items until this much space is freed (e.g. 20GB)")
+	cleanCmd.Flags().BoolVar(&includeCaution, "include-caution", false, "With --all, also clean caution items")
+	cleanCmd.Flags().BoolVar(&includeWarning, "include-warning", false, "With --all, also clean caution and warning items")
+	cleanCmd.Flags().Float64Var(&targetFreePercent, "target-free-percent", 0, "Stop cleaning a filesystem once this percentage of it is free")
+	addStrategyFlags(cleanCmd)
+	addForceFlag(cleanCmd)
+	addCategoryFlags(cleanCmd, &cleanOnly, &cleanSkip)
+	addProfileFlag(cleanCmd)
+	addSelectFlag(cleanCmd)
+	addOutputFlag(cleanCmd)
 
-	// Antigravity-specific command
-	var agCmd = &cobra.Command{
-		Use:   "antigravity",
-		Short: "Clean Antigravity IDE specific caches",
-		Long:  "Clean Google Antigravity IDE session recordings, conversations, and caches.",
-		Run: func(cmd *cobra.Command, args []string) {
-			results := scanner.ScanAntigravity()
-			if len(results) == 0 {
-				fmt.Println("No Antigravity cleanable items found.")
-				return
-			}
-			toClean := ui.SelectItems(results)
-			if len(toClean) > 0 {
-				cleaner.CleanItems(toClean)
-			}
-		},
+	rootCmd.AddCommand(scanCmd, cleanCmd, newRestoreCmd(), newQuarantineCmd(), newHistoryCmd(), newRulesCmd(), newCacheCmd(), newConfigCmd(), newProfileCmd())
+
+	// Rule files and custom paths may add categories, so load them before
+	// building the per-provider subcommands
+	activeRules, err := rules.Load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "⚠️  Some rule files were skipped; run 'agc rules validate' for details")
 	}
+	scanner.UseRules(rules.Merge(activeRules, cfg.Rules()))
 
-	// Flutter-specific command
-	var flutterPath string
-	var flutterCmd = &cobra.Command{
-		Use:   "flutter",
-		Short: "Clean Flutter project build directories",
-		Long:  "Scan and clean Flutter project build directories, .dart_tool, and Pods.",
-		Run: func(cmd *cobra.Command, args []string) {
-			results := scanner.ScanFlutter(flutterPath)
-			if len(results) == 0 {
-				fmt.Println("No Flutter cleanable items found.")
-				return
-			}
-			toClean := ui.SelectItems(results)
-			if len(toClean) > 0 {
-				cleaner.CleanItems(toClean)
-			}
-		},
+	// One subcommand per provider, e.g. "agc flutter" or "agc xcode"
+	for _, p := range scanner.Providers() {
+		rootCmd.AddCommand(newProviderCmd(p))
 	}
-	flutterCmd.Flags().StringVarP(&flutterPath, "path", "p", "", "Path to scan for Flutter projects (default: ~/Documents)")
 
-	// Xcode-specific command
-	var xcodeCmd = &cobra.Command{
-		Use:   "xcode",
-		Short: "Clean Xcode caches and derived data",
-		Long:  "Clean Xcode DerivedData, iOS DeviceSupport, and archives.",
-		Run: func(cmd *cobra.Command, args []string) {
-			results := scanner.ScanXcode()
-			if len(results) == 0 {
-				fmt.Println("No Xcode cleanable items found.")
-				return
-			}
-			toClean := ui.SelectItems(results)
-			if len(toClean) > 0 {
-				cleaner.CleanItems(toClean)
-			}
-		},
+	if err := rootCmd.ExecuteContext(ctx); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(exitError)
+	}
+	os.Exit(exitCode)
+}
+
+// cleanToFree plans which items to clean to free target bytes, shows the
+// plan and cleans it once confirmed. held is the size of the items held
+// back above level, mentioned when the target can't be met without them.
+// yes skips the confirmation.
+func cleanToFree(result scanner.Result, target, held int64, level string, yes, dryRun bool) error {
+	plan := cleaner.PlanFree(result.Items, target, time.Now())
+	plan.HeldBack, plan.HeldAbove = held, level
+	if len(plan.Items) == 0 {
+		exitCode = exitNothing
+	}
+
+	if outputFormat != "text" {
+		if dryRun {
+			return report.Write(os.Stdout, outputFormat, report.NewDocument("dry-run", result, applyFreeTarget(plan.Items)))
+		}
+		return runClean(plan.Items, "")
+	}
+
+	if len(plan.Items) == 0 {
+		fmt.Println("No cleanable items found.")
+		return nil
+	}
+	ui.DisplayFreePlan(plan)
+	if dryRun {
+		applyFreeTarget(plan.Items)
+		return nil
+	}
+	if !yes {
+		var confirmed bool
+		if plan.MaxSafety == "warning" {
+			// Warning items need the typed confirmation instead of y/N
+			confirmed = ui.ConfirmRisky(plan.Items)
+		} else {
+			fmt.Println()
+			confirmed = ui.Confirm(fmt.Sprintf("Clean these %s?", ui.Pluralize(len(plan.Items), "item")))
+		}
+		if !confirmed {
+			fmt.Println("Nothing cleaned.")
+			exitCode = exitNothing
+			return nil
+		}
+		confirmInUse(plan.Items)
+	}
+	return runClean(plan.Items, "")
+}
+
+// applyFreeTarget drops the items --target-free-percent would skip, for
+// dry runs, which can't watch the free space grow
+func applyFreeTarget(items []scanner.CleanableItem) []scanner.CleanableItem {
+	if targetFreePercent <= 0 {
+		return items
+	}
+	kept := cleaner.PlanFreePercent(items, targetFreePercent, time.Now())
+	if skipped := len(items) - len(kept); skipped > 0 && outputFormat == "text" {
+		fmt.Printf("🎯 %s left out: their filesystems reach %g%% free without them\n", ui.Pluralize(skipped, "item"), targetFreePercent)
+	}
+	return kept
+}
+
+// runClean removes items, printing each outcome as it happens in text
+// mode, then renders the report and sets the exit code from it. emptyMsg
+// is shown when there is nothing to clean.
+func runClean(items []scanner.CleanableItem, emptyMsg string) error {
+	opts := cleaner.Options{TargetFreePercent: targetFreePercent, Strategy: cleaner.Strategy(strategy), Force: force}
+	if outputFormat == "text" {
+		if len(items) == 0 {
+			fmt.Println(emptyMsg)
+		} else {
+			fmt.Println("\n🧹 Cleaning...")
+			opts.Progress = ui.DisplayCleanResult
+		}
+	}
+
+	cleanReport := cleaner.CleanItems(items, opts)
+	exitCode = cleanExitCodes[cleanReport.Status()]
+	recordHistory(history.FromClean(cleanReport, commandLine(), version))
+	if outputFormat != "text" {
+		return report.WriteClean(os.Stdout, outputFormat, report.NewCleanDocument(cleanReport))
+	}
+	if len(items) > 0 {
+		ui.DisplayCleanReport(cleanReport)
+	}
+	return nil
+}
+
+// recordHistory appends records to the cleanup journal. A journal that
+// can't be written is reported but doesn't fail the command, which has
+// already done its work.
+func recordHistory(records []history.Record) {
+	if err := history.Append(history.DefaultPath(), records); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Could not write history: %v\n", err)
+	}
+}
+
+// commandLine returns the agc command line as typed, for the journal
+func commandLine() string {
+	args := []string{"agc"}
+	for _, arg := range os.Args[1:] {
+		if arg == "" || strings.ContainsAny(arg, " \t\"'") {
+			arg = strconv.Quote(arg)
+		}
+		args = append(args, arg)
+	}
+	return strings.Join(args, " ")
+}
+
+// configFlag finds --config in args ahead of cobra's parsing, since the
+// configuration decides which subcommands exist
+func configFlag(args []string) string {
+	flags := pflag.NewFlagSet("config", pflag.ContinueOnError)
+	flags.ParseErrorsWhitelist.UnknownFlags = true
+	flags.SetOutput(io.Discard)
+	path := flags.String("config", config.DefaultPath(), "")
+	_ = flags.Parse(args)
+	return *path
+}
+
+// setUnchangedFlags sets each named flag the command has to its value,
+// unless it was given on the command line or already set. A local flag
+// shadowing a global one, like --older-than of quarantine purge, means
+// something else and is left alone.
+func setUnchangedFlags(cmd *cobra.Command, values map[string]string) error {
+	for name, value := range values {
+		if cmd.Flags().Lookup(name) == nil || cmd.Flags().Changed(name) {
+			continue
+		}
+		if cmd.LocalNonPersistentFlags().Lookup(name) != nil && cmd.Root().PersistentFlags().Lookup(name) != nil {
+			continue
+		}
+		if err := cmd.Flags().Set(name, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addProfileFlag registers --profile
+func addProfileFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&profileName, "profile", "", "Apply a named cleanup profile (see 'agc profile list')")
+}
+
+// addOutputFlag registers --output
+func addOutputFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "text", "Output format: "+strings.Join(report.Formats, ", "))
+}
+
+// addStrategyFlags registers --strategy and --permanent
+func addStrategyFlags(cmd *cobra.Command) {
+	var names []string
+	for _, s := range cleaner.Strategies {
+		names = append(names, string(s))
+	}
+	cmd.Flags().StringVar(&strategy, "strategy", string(cleaner.Quarantine),
+		"How items are removed: "+strings.Join(names, ", ")+"; quarantined items can be put back with 'agc restore'")
+	cmd.Flags().BoolVar(&permanent, "permanent", false, "Delete items right away, like --strategy delete")
+}
+
+// addForceFlag registers --force
+func addForceFlag(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&force, "force", false, "Clean items even while the applications owning them are running")
+}
+
+// confirmInUse asks whether to clean the items running processes are
+// using. Confirming cleans them as --force would; otherwise the cleaner
+// skips them.
+func confirmInUse(items []scanner.CleanableItem) {
+	if force {
+		return
+	}
+	if found := cleaner.FindInUse(items); len(found) > 0 && ui.ConfirmInUse(found) {
+		force = true
+	}
+}
+
+// applyPermanent turns --permanent into --strategy delete. It runs before
+// the profile and defaults are applied, so like any command-line flag it
+// wins over them.
+func applyPermanent(cmd *cobra.Command) error {
+	if !permanent || cmd.Flags().Lookup("strategy") == nil {
+		return nil
+	}
+	if cmd.Flags().Changed("strategy") && strategy != string(cleaner.Delete) {
+		return fmt.Errorf("--permanent conflicts with --strategy %s", strategy)
+	}
+	return cmd.Flags().Set("strategy", string(cleaner.Delete))
+}
+
+// freeingStrategy makes --free and --target-free-percent delete items:
+// quarantined and trashed items stay on disk, so they would free nothing.
+// A strategy set explicitly, by a flag, profile or default, must be delete.
+func freeingStrategy(cmd *cobra.Command, freeing bool) error {
+	if !freeing {
+		return nil
+	}
+	if !cmd.Flags().Changed("strategy") {
+		strategy = string(cleaner.Delete)
+		return nil
+	}
+	if strategy != string(cleaner.Delete) {
+		return fmt.Errorf("--free and --target-free-percent delete items to release space; they can't be used with --strategy %s", strategy)
+	}
+	return nil
+}
+
+// checkStrategy validates --strategy
+func checkStrategy(cmd *cobra.Command) error {
+	if cmd.Flags().Lookup("strategy") == nil {
+		return nil
+	}
+	for _, s := range cleaner.Strategies {
+		if strategy == string(s) {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid --strategy %q (use quarantine, trash or delete)", strategy)
+}
+
+// compileSelect compiles --select into selectFilter. A profile's select
+// expression doesn't give way to --select like its other settings; items
+// have to match both.
+func compileSelect() error {
+	expr := selectExpr
+	if selectExpr != "" {
+		if _, err := filter.Parse(selectExpr); err != nil {
+			return fmt.Errorf("invalid --select: %w", err)
+		}
+	}
+	if activeProfile != nil && activeProfile.Select != "" {
+		if _, err := filter.Parse(activeProfile.Select); err != nil {
+			return fmt.Errorf("profile %s: select: %w", activeProfile.Name, err)
+		}
+		if expr == "" {
+			expr = activeProfile.Select
+		} else {
+			expr = "(" + activeProfile.Select + ") and (" + expr + ")"
+		}
+	}
+	if expr == "" {
+		return nil
+	}
+	f, err := filter.Parse(expr)
+	if err != nil {
+		return err
+	}
+	selectFilter = f
+	return nil
+}
+
+// addSelectFlag registers --select
+func addSelectFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&selectExpr, "select", "", `Only include items matching an expression, e.g. "category in (Flutter,Android) and size > 1GB and age > 14d"`)
+}
+
+// keepItem reports whether item passes the active profile's safety limit
+// and the --select expression
+func keepItem(item scanner.CleanableItem, now time.Time) bool {
+	if activeProfile != nil && !activeProfile.Allows(item.SafeLevel) {
+		return false
+	}
+	return selectFilter == nil || selectFilter.Match(item, now)
+}
+
+// applyFilters drops the items the active profile or --select rule out,
+// saying how many in text mode
+func applyFilters(result *scanner.Result) {
+	if activeProfile == nil && selectFilter == nil {
+		return
 	}
+	now := time.Now()
+	kept := result.Items[:0]
+	riskier, unmatched := 0, 0
+	for _, item := range result.Items {
+		switch {
+		case activeProfile != nil && !activeProfile.Allows(item.SafeLevel):
+			riskier++
+		case selectFilter != nil && !selectFilter.Match(item, now):
+			unmatched++
+		default:
+			kept = append(kept, item)
+		}
+	}
+	result.Items = kept
+
+	if outputFormat != "text" {
+		return
+	}
+	if activeProfile != nil {
+		fmt.Printf("📋 Profile %s: %s\n", activeProfile.Name, activeProfile.Description)
+		if riskier > 0 {
+			fmt.Printf("   Skipping %s above %s safety level\n", ui.Pluralize(riskier, "item"), activeProfile.MaxSafety)
+		}
+	}
+	if selectFilter != nil {
+		fmt.Printf("🔎 Selecting %s: %s left out\n", selectFilter, ui.Pluralize(unmatched, "item"))
+	}
+	fmt.Println()
+}
+
+// unattendedLevel returns the riskiest safety level clean --all removes:
+// safe unless --include-caution or --include-warning, or the active
+// profile's include fields, allow more. The profile's max_safety can only
+// lower it.
+func unattendedLevel(includeCaution, includeWarning bool) string {
+	var p profile.Profile
+	if activeProfile != nil {
+		p = *activeProfile
+	}
+	return p.UnattendedLevel(includeCaution, includeWarning)
+}
+
+// holdBackRisky drops the items above level, saying how many in text mode,
+// and returns the rest with the on-disk size of those dropped
+func holdBackRisky(items []scanner.CleanableItem, level string) ([]scanner.CleanableItem, int64) {
+	var kept []scanner.CleanableItem
+	var held int
+	var heldBytes int64
+	for _, item := range items {
+		if rules.SafetyRank(item.SafeLevel) > rules.SafetyRank(level) {
+			held++
+			heldBytes += item.DiskSize
+			continue
+		}
+		kept = append(kept, item)
+	}
+	if held > 0 && outputFormat == "text" {
+		fmt.Printf("🔒 Holding back %s above %s level (%s); add %s to clean them\n\n",
+			ui.Pluralize(held, "item"), level, humanize.Bytes(uint64(heldBytes)), ui.IncludeHint(level))
+	}
+	return kept, heldBytes
+}
+
+// addCategoryFlags registers the --only and --skip category filters
+func addCategoryFlags(cmd *cobra.Command, only, skip *[]string) {
+	cmd.Flags().StringSliceVar(only, "only", nil, "Only scan these categories (e.g. flutter,xcode)")
+	cmd.Flags().StringSliceVar(skip, "skip", nil, "Skip these categories")
+}
+
+// newProviderCmd builds the subcommand that scans and cleans a single provider
+func newProviderCmd(p scanner.Provider) *cobra.Command {
+	var basePath string
+	cmd := &cobra.Command{
+		Use:   p.ID(),
+		Short: p.Description(),
+		Long:  p.Description() + ".",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !scanner.Supports(p, runtime.GOOS) {
+				return fmt.Errorf("%s is only available on %s", p.ID(), strings.Join(p.Platforms(), ", "))
+			}
 
-	// Simulator command
-	var simCmd = &cobra.Command{
-		Use:   "simulator",
-		Short: "Clean old simulator runtimes",
-		Long:  "Remove unavailable iOS/watchOS/tvOS simulator runtimes and devices.",
-		Run: func(cmd *cobra.Command, args []string) {
-			results := scanner.ScanSimulators()
+			result := runScan(cmd.Context(), nil, func(ctx context.Context, s *scanner.Session) scanner.Result {
+				return scanner.ScanProvider(ctx, s, p, basePath)
+			})
+			if err := checkScan(result); err != nil {
+				return err
+			}
+			results := result.Items
 			if len(results) == 0 {
-				fmt.Println("No old simulator runtimes found.")
-				return
+				return runClean(nil, fmt.Sprintf("No %s cleanable items found.", p.Name()))
 			}
-			toClean := ui.SelectItems(results)
-			if len(toClean) > 0 {
-				cleaner.CleanSimulators(toClean)
+			selected := ui.SelectItems(results)
+			if !ui.ConfirmRisky(selected) {
+				fmt.Println("Nothing cleaned.")
+				exitCode = exitNothing
+				return nil
 			}
+			confirmInUse(selected)
+			return runClean(selected, "No items selected for cleaning.")
 		},
 	}
+	addStrategyFlags(cmd)
+	addForceFlag(cmd)
+	if _, ok := p.(scanner.PathScanner); ok {
+		cmd.Flags().StringVarP(&basePath, "path", "p", "", "Path to scan for projects (default: ~/Documents)")
+	}
+	return cmd
+}
 
-	rootCmd.AddCommand(scanCmd, cleanCmd, agCmd, flutterCmd, xcodeCmd, simCmd)
-
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
 	}
+	return false
 }
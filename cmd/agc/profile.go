@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/iml1s/antigravity-cleaner/internal/profile"
+	"github.com/spf13/cobra"
+)
+
+// newProfileCmd builds "agc profile" and its list subcommand
+func newProfileCmd() *cobra.Command {
+	profileCmd := &cobra.Command{
+		Use:   "profile",
+		Short: "Inspect the named cleanup profiles",
+		Long: `Profiles bundle the categories, maximum safety level, age and size filters and
+strategy of a recurring cleanup. Run one with 'agc clean --profile NAME'.
+Profiles are defined under "profiles:" in the configuration file, where a
+profile named like a built-in one replaces it.`,
+	}
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the available profiles and their settings",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if cfgErr != nil {
+				return cfgErr
+			}
+			profiles := cfg.AllProfiles()
+			for _, name := range profile.Names(profiles) {
+				p := profiles[name]
+				fmt.Printf("📋 %s (%s)\n", name, p.Source)
+				if p.Description != "" {
+					fmt.Printf("   %s\n", p.Description)
+				}
+				if settings := profileSettings(p); settings != "" {
+					fmt.Printf("   %s\n", settings)
+				}
+				fmt.Println()
+			}
+			return nil
+		},
+	}
+
+	profileCmd.AddCommand(listCmd)
+	return profileCmd
+}
+
+// profileSettings summarizes the flags a profile sets
+func profileSettings(p profile.Profile) string {
+	var parts []string
+	if p.MaxSafety != "" {
+		parts = append(parts, "max safety "+p.MaxSafety)
+	}
+	flags := p.Flags()
+	names := make([]string, 0, len(flags))
+	for name := range flags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if flags[name] == "true" {
+			parts = append(parts, "--"+name)
+		} else {
+			parts = append(parts, fmt.Sprintf("--%s %s", name, flags[name]))
+		}
+	}
+	return strings.Join(parts, " · ")
+}
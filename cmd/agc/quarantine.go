@@ -0,0 +1,133 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/iml1s/antigravity-cleaner/internal/history"
+	"github.com/iml1s/antigravity-cleaner/internal/quarantine"
+	"github.com/iml1s/antigravity-cleaner/internal/ui"
+	"github.com/iml1s/antigravity-cleaner/internal/units"
+	"github.com/spf13/cobra"
+)
+
+// newRestoreCmd builds "agc restore"
+func newRestoreCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "restore <id|path>...",
+		Short: "Put quarantined items back where they were",
+		Long: `Move quarantined items back to their original location. Items are named by
+the ID shown by 'agc quarantine list' or by their original path.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := quarantine.Open(quarantine.DefaultDir())
+			if err != nil {
+				return err
+			}
+			failed := 0
+			var records []history.Record
+			for _, ref := range args {
+				entry, err := store.Restore(ref)
+				if err != nil {
+					fmt.Printf("❌ %v\n", err)
+					failed++
+					continue
+				}
+				fmt.Printf("✓ Restored %s (%s)\n", entry.Path, humanize.Bytes(uint64(entry.Size)))
+				records = append(records, history.FromQuarantine(entry, history.Restored, commandLine(), version))
+			}
+			recordHistory(records)
+			if failed > 0 {
+				return fmt.Errorf("%s could not be restored", ui.Pluralize(failed, "item"))
+			}
+			return nil
+		},
+	}
+}
+
+// newQuarantineCmd builds "agc quarantine" and its subcommands
+func newQuarantineCmd() *cobra.Command {
+	quarantineCmd := &cobra.Command{
+		Use:   "quarantine",
+		Short: "List or purge quarantined items",
+		Long: `agc clean moves items into a quarantine on their own filesystem instead of
+deleting them, so 'agc restore' can put them back. Their space is only
+released once they are purged.`,
+	}
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List quarantined items",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := quarantine.Open(quarantine.DefaultDir())
+			if err != nil {
+				return err
+			}
+			entries := store.Entries()
+			if len(entries) == 0 {
+				fmt.Println("The quarantine is empty.")
+				return nil
+			}
+			now := time.Now()
+			var total int64
+			for _, e := range entries {
+				total += e.Size
+				fmt.Printf("📦 %s  %5s  %10s  %-12s %s\n", e.ID, units.FormatAge(now.Sub(e.Time)),
+					humanize.Bytes(uint64(e.Size)), e.Category, e.Path)
+			}
+			fmt.Printf("\n📊 %s, %s in quarantine\n", ui.Pluralize(len(entries), "item"), humanize.Bytes(uint64(total)))
+			return nil
+		},
+	}
+
+	var purgeOlderThan string
+	var purgeAll bool
+	purgeCmd := &cobra.Command{
+		Use:   "purge",
+		Short: "Delete quarantined items for good",
+		Long:  "Delete quarantined items, releasing their space. Give --older-than to keep recent items, or --all.",
+		Example: `  agc quarantine purge --older-than 7d
+  agc quarantine purge --all`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var age time.Duration
+			switch {
+			case purgeOlderThan != "" && purgeAll:
+				return errors.New("use either --older-than or --all")
+			case purgeOlderThan != "":
+				d, err := units.ParseDuration(purgeOlderThan)
+				if err != nil || d <= 0 {
+					return fmt.Errorf("invalid --older-than %q (use e.g. 7d, 2w)", purgeOlderThan)
+				}
+				age = d
+			case !purgeAll:
+				return errors.New("give --older-than (e.g. 7d) or --all")
+			}
+
+			store, err := quarantine.Open(quarantine.DefaultDir())
+			if err != nil {
+				return err
+			}
+			purged, err := store.Purge(age, time.Now())
+			var freed int64
+			records := make([]history.Record, 0, len(purged))
+			for _, e := range purged {
+				freed += e.Size
+				records = append(records, history.FromQuarantine(e, history.Purged, commandLine(), version))
+			}
+			recordHistory(records)
+			fmt.Printf("✨ Purged %s, freed %s\n", ui.Pluralize(len(purged), "item"), humanize.Bytes(uint64(freed)))
+			if left := len(store.Entries()); left > 0 {
+				fmt.Printf("📦 %s left in quarantine\n", ui.Pluralize(left, "item"))
+			}
+			return err
+		},
+	}
+	// Shadows the global --older-than, which filters scans instead
+	purgeCmd.Flags().StringVar(&purgeOlderThan, "older-than", "", "Only purge items quarantined at least this long ago (e.g. 7d)")
+	purgeCmd.Flags().BoolVar(&purgeAll, "all", false, "Purge every quarantined item")
+
+	quarantineCmd.AddCommand(listCmd, purgeCmd)
+	return quarantineCmd
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+
+	"github.com/iml1s/antigravity-cleaner/internal/rules"
+	"github.com/iml1s/antigravity-cleaner/internal/scanner"
+	"github.com/iml1s/antigravity-cleaner/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+// newRulesCmd builds "agc rules" and its list/validate subcommands
+func newRulesCmd() *cobra.Command {
+	rulesCmd := &cobra.Command{
+		Use:   "rules",
+		Short: "Inspect the rules that describe cleanable paths",
+		Long: fmt.Sprintf(`Rules describe the cleanable paths agc knows about. Built-in rules ship with
+agc; extra rule files (*.yaml) are loaded from:
+
+  %s
+
+A user rule with the same id as a built-in rule replaces it.`, rules.UserDir()),
+	}
+
+	var listAll bool
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List active rules and the paths they expand to",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			goos := runtime.GOOS
+			if listAll {
+				goos = ""
+			}
+			ui.DisplayRules(scanner.ActiveRules(), goos)
+			return nil
+		},
+	}
+	listCmd.Flags().BoolVar(&listAll, "all-platforms", false, "Show path templates for every platform instead of expanded paths")
+
+	validateCmd := &cobra.Command{
+		Use:   "validate [file...]",
+		Short: "Check rule files for errors",
+		Long:  "Validate the given rule files, or the built-in rules and every file in the user rules directory.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var failed bool
+			report := func(source string, rs []rules.Rule, err error) {
+				if err != nil {
+					failed = true
+					fmt.Printf("❌ %v\n", err)
+					return
+				}
+				fmt.Printf("✓ %s (%d rules)\n", source, len(rs))
+			}
+
+			if len(args) == 0 {
+				builtin, err := rules.Builtin()
+				report("built-in rules", builtin, err)
+				files, err := rules.Files(rules.UserDir())
+				if err != nil {
+					return err
+				}
+				args = files
+			}
+			for _, path := range args {
+				rs, err := rules.LoadFile(path)
+				report(path, rs, err)
+			}
+
+			if failed {
+				return errors.New("rule validation failed")
+			}
+			return nil
+		},
+	}
+
+	rulesCmd.AddCommand(listCmd, validateCmd)
+	return rulesCmd
+}
@@ -1,77 +1,283 @@
 package cleaner
 
 import (
-	"fmt"
 	"os"
 	"os/exec"
-	"runtime"
+	"time"
 
-	"github.com/dustin/go-humanize"
+	"github.com/iml1s/antigravity-cleaner/internal/procs"
+	"github.com/iml1s/antigravity-cleaner/internal/quarantine"
 	"github.com/iml1s/antigravity-cleaner/internal/scanner"
+	"github.com/iml1s/antigravity-cleaner/internal/sizecache"
+	"github.com/iml1s/antigravity-cleaner/internal/trash"
 )
 
-// CleanItems removes the specified cleanable items
-func CleanItems(items []scanner.CleanableItem) {
-	var totalCleaned int64
-	var successCount, failCount int
+// Outcome is what happened to one item
+type Outcome string
 
-	fmt.Println("\n🧹 Cleaning...")
+const (
+	Removed     Outcome = "removed"
+	Quarantined Outcome = "quarantined"
+	Trashed     Outcome = "trashed"
+	Failed      Outcome = "failed"
+	Skipped     Outcome = "skipped"
+)
 
-	for _, item := range items {
-		fmt.Printf("  Removing %s... ", item.Description)
-
-		err := os.RemoveAll(item.Path)
-		if err != nil {
-			fmt.Printf("❌ Failed: %v\n", err)
-			failCount++
-		} else {
-			fmt.Printf("✓ %s freed\n", humanize.Bytes(uint64(item.Size)))
-			totalCleaned += item.Size
-			successCount++
+// Strategy is how CleanItems disposes of items
+type Strategy string
+
+const (
+	// Quarantine moves items into the agc quarantine, from which agc
+	// restore can put them back
+	Quarantine Strategy = "quarantine"
+	// Trash moves items to the system trash
+	Trash Strategy = "trash"
+	// Delete removes items right away
+	Delete Strategy = "delete"
+)
+
+// Strategies lists the accepted strategies, the default first
+var Strategies = []Strategy{Quarantine, Trash, Delete}
+
+// ItemResult records how cleaning one item went
+type ItemResult struct {
+	Item    scanner.CleanableItem
+	Outcome Outcome
+	// BytesFreed is the item's on-disk size if it was removed. Trashed and
+	// quarantined items free nothing until the trash is emptied or the
+	// quarantine purged.
+	BytesFreed int64
+	// TrashPath is where a Trashed item was moved to
+	TrashPath string
+	// QuarantineID identifies a Quarantined item for agc restore
+	QuarantineID string
+	Err          error
+	// Reason explains why a Skipped item was left alone
+	Reason string
+	// InUse lists the processes a Skipped item was left alone for
+	InUse    []procs.Process
+	Duration time.Duration
+}
+
+// Status summarizes a whole clean run
+type Status int
+
+const (
+	// Success means every item attempted was removed, quarantined or
+	// trashed
+	Success Status = iota
+	// Partial means some items were removed and some failed
+	Partial
+	// AllFailed means no item could be removed
+	AllFailed
+	// NothingToDo means there were no items to clean, or all were skipped
+	NothingToDo
+)
+
+// CleanReport is the result of CleanItems
+type CleanReport struct {
+	Items      []ItemResult
+	BytesFreed int64
+	// BytesQuarantined and BytesTrashed are the on-disk sizes of the items
+	// moved to the quarantine and to the trash
+	BytesQuarantined int64
+	BytesTrashed     int64
+	Duration         time.Duration
+	// CacheErr is set when the size cache couldn't be updated afterwards
+	CacheErr error
+}
+
+// Count returns the number of items with the given outcome
+func (r CleanReport) Count(outcome Outcome) int {
+	n := 0
+	for _, item := range r.Items {
+		if item.Outcome == outcome {
+			n++
 		}
 	}
+	return n
+}
 
-	fmt.Println()
-	fmt.Printf("✨ Done! Cleaned %d items, freed %s\n", successCount, humanize.Bytes(uint64(totalCleaned)))
-	if failCount > 0 {
-		fmt.Printf("⚠️  %d items failed to clean\n", failCount)
+// Status classifies the run by how many of the items attempted failed
+func (r CleanReport) Status() Status {
+	failed := r.Count(Failed)
+	attempted := len(r.Items) - r.Count(Skipped)
+	switch {
+	case attempted == 0:
+		return NothingToDo
+	case failed == 0:
+		return Success
+	case failed == attempted:
+		return AllFailed
+	default:
+		return Partial
 	}
 }
 
-// CleanSimulators removes unavailable simulator runtimes and devices
-func CleanSimulators(items []scanner.CleanableItem) {
-	if runtime.GOOS != "darwin" {
-		fmt.Println("Simulator cleanup is only available on macOS")
-		return
+// Options controls a clean run
+type Options struct {
+	// Progress, if set, is called after each item is handled
+	Progress func(ItemResult)
+	// TargetFreePercent, when positive, skips items on filesystems that
+	// already have this percentage of their space free. Items are then
+	// cleaned in SortForFreeing order, so the riskier ones are left.
+	// Quarantined and trashed items stay on disk and don't count towards
+	// the target.
+	TargetFreePercent float64
+	// Strategy is how items are disposed of; empty means Quarantine
+	Strategy Strategy
+	// Force cleans items even while a process is using them. Otherwise
+	// they are skipped, naming the processes, as FindInUse would report.
+	Force bool
+}
+
+// CleanItems quarantines, trashes or deletes the specified cleanable items,
+// as Options.Strategy says, and reports what happened to each. Each path is
+// checked again first, and fails without being touched if it no longer
+// looks like what was scanned. Items belonging to a provider with custom
+// removal logic are handed to that provider instead.
+func CleanItems(items []scanner.CleanableItem, opts Options) CleanReport {
+	start := time.Now()
+	var report CleanReport
+	var target *freeTarget
+	if opts.TargetFreePercent > 0 {
+		target = newFreeTarget(opts.TargetFreePercent)
+		items = SortForFreeing(items, start)
+	}
+	var plain []scanner.CleanableItem
+	custom := make(map[string][]scanner.CleanableItem)
+	var customOrder []string
+
+	for _, item := range items {
+		if _, ok := scanner.Lookup(item.Category).(scanner.Cleaner); ok {
+			if _, seen := custom[item.Category]; !seen {
+				customOrder = append(customOrder, item.Category)
+			}
+			custom[item.Category] = append(custom[item.Category], item)
+			continue
+		}
+		plain = append(plain, item)
 	}
 
-	fmt.Println("\n🧹 Cleaning simulators...")
+	var store *quarantine.Store
+	var storeErr error
+	if opts.Strategy == "" || opts.Strategy == Quarantine {
+		store, storeErr = quarantine.Open(quarantine.DefaultDir())
+	}
 
-	// Delete unavailable devices
-	fmt.Print("  Removing unavailable devices... ")
-	cmd := exec.Command("xcrun", "simctl", "delete", "unavailable")
-	err := cmd.Run()
-	if err != nil {
-		fmt.Printf("❌ Failed: %v\n", err)
-	} else {
-		fmt.Println("✓")
+	inUse := make(map[string][]procs.Process)
+	if !opts.Force {
+		for _, found := range FindInUse(plain) {
+			inUse[found.Item.Path] = found.Processes
+		}
 	}
 
-	// For runtime deletion, we need to handle each runtime specifically
-	for _, item := range items {
-		if item.Category == "Simulator" {
-			fmt.Printf("  Removing %s... ", item.Description)
-			cmd := exec.Command("xcrun", "simctl", "runtime", "delete", item.Path)
-			err := cmd.Run()
-			if err != nil {
-				fmt.Printf("❌ Failed: %v\n", err)
-			} else {
-				fmt.Printf("✓ %s freed\n", humanize.Bytes(uint64(item.Size)))
+	var removed []string
+	for _, item := range plain {
+		if using := inUse[item.Path]; len(using) > 0 {
+			report.add(ItemResult{Item: item, Outcome: Skipped, Reason: inUseReason(using), InUse: using}, opts)
+			continue
+		}
+		if target != nil {
+			if reason := target.skip(item); reason != "" {
+				report.add(ItemResult{Item: item, Outcome: Skipped, Reason: reason}, opts)
+				continue
+			}
+		}
+		itemStart := time.Now()
+		if err := checkPath(item); err != nil {
+			report.add(newResult(item, err, time.Since(itemStart)), opts)
+			continue
+		}
+		var result ItemResult
+		switch opts.Strategy {
+		case Delete:
+			result = newResult(item, os.RemoveAll(item.Path), time.Since(itemStart))
+		case Trash:
+			trashPath, err := trash.Move(item.Path, item.Mount)
+			result = newResult(item, err, time.Since(itemStart))
+			if err == nil {
+				result.Outcome, result.BytesFreed, result.TrashPath = Trashed, 0, trashPath
 			}
+		default:
+			err := storeErr
+			var entry quarantine.Entry
+			if err == nil {
+				entry, err = store.Add(item)
+			}
+			result = newResult(item, err, time.Since(itemStart))
+			if err == nil {
+				result.Outcome, result.BytesFreed, result.QuarantineID = Quarantined, 0, entry.ID
+			}
+		}
+		if result.Outcome != Failed {
+			removed = append(removed, item.Path)
 		}
+		report.add(result, opts)
 	}
 
-	fmt.Println("\n✨ Simulator cleanup complete!")
+	// Cached listings of removed trees, and of their parents, are now stale
+	if len(removed) > 0 {
+		report.CacheErr = sizecache.Invalidate(sizecache.DefaultPath(), removed)
+	}
+
+	for _, category := range customOrder {
+		var batch []scanner.CleanableItem
+		for _, item := range custom[category] {
+			if target != nil {
+				// The provider removes the whole batch at once, so count
+				// what it will free before it runs
+				if reason := target.skip(item); reason != "" {
+					report.add(ItemResult{Item: item, Outcome: Skipped, Reason: reason}, opts)
+					continue
+				}
+				target.pending[item.Mount] += item.DiskSize
+			}
+			batch = append(batch, item)
+		}
+		if len(batch) == 0 {
+			continue
+		}
+		batchStart := time.Now()
+		errs := scanner.Lookup(category).(scanner.Cleaner).Clean(batch)
+		// The provider handles the batch at once, so split its time evenly
+		each := time.Since(batchStart) / time.Duration(len(batch))
+		for i, item := range batch {
+			var err error
+			if i < len(errs) {
+				err = errs[i]
+			}
+			report.add(newResult(item, err, each), opts)
+			if target != nil {
+				target.pending[item.Mount] -= item.DiskSize
+			}
+		}
+	}
+
+	report.Duration = time.Since(start)
+	return report
+}
+
+func newResult(item scanner.CleanableItem, err error, d time.Duration) ItemResult {
+	result := ItemResult{Item: item, Outcome: Removed, BytesFreed: item.DiskSize, Duration: d}
+	if err != nil {
+		result.Outcome, result.BytesFreed, result.Err = Failed, 0, err
+	}
+	return result
+}
+
+func (r *CleanReport) add(result ItemResult, opts Options) {
+	r.Items = append(r.Items, result)
+	r.BytesFreed += result.BytesFreed
+	switch result.Outcome {
+	case Quarantined:
+		r.BytesQuarantined += result.Item.DiskSize
+	case Trashed:
+		r.BytesTrashed += result.Item.DiskSize
+	}
+	if opts.Progress != nil {
+		opts.Progress(result)
+	}
 }
 
 // CleanFlutterProject runs flutter clean in a project directory
@@ -0,0 +1,121 @@
+package cleaner
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/iml1s/antigravity-cleaner/internal/fsinfo"
+	"github.com/iml1s/antigravity-cleaner/internal/rules"
+	"github.com/iml1s/antigravity-cleaner/internal/scanner"
+)
+
+// FilesystemUsage is the space items take up on one filesystem
+type FilesystemUsage struct {
+	fsinfo.Filesystem
+	Items int
+	// Bytes is the on-disk size of the items
+	Bytes int64
+	// Err is set when the free space couldn't be read, leaving Total and
+	// Free at zero
+	Err error
+}
+
+// FreeAfter returns the free space projected once the items are removed
+func (u FilesystemUsage) FreeAfter() int64 {
+	return u.Free + u.Bytes
+}
+
+// Filesystems groups items by the filesystem holding them, sorted by mount
+// point, and reads the current free space of each. Items whose mount
+// couldn't be resolved are left out.
+func Filesystems(items []scanner.CleanableItem) []FilesystemUsage {
+	byMount := make(map[string]*FilesystemUsage)
+	var mounts []string
+	for _, item := range items {
+		if item.Mount == "" {
+			continue
+		}
+		u, ok := byMount[item.Mount]
+		if !ok {
+			u = &FilesystemUsage{}
+			u.Filesystem, u.Err = fsinfo.Stat(item.Mount)
+			byMount[item.Mount] = u
+			mounts = append(mounts, item.Mount)
+		}
+		u.Items++
+		u.Bytes += item.DiskSize
+	}
+
+	sort.Strings(mounts)
+	result := make([]FilesystemUsage, 0, len(mounts))
+	for _, mount := range mounts {
+		result = append(result, *byMount[mount])
+	}
+	return result
+}
+
+// SortForFreeing orders items the way PlanFree picks them: safe items
+// before caution before warning, and within a level older items before
+// newer and larger before smaller. Items used on the same day count as
+// equally old, so size decides between them.
+func SortForFreeing(items []scanner.CleanableItem, now time.Time) []scanner.CleanableItem {
+	sorted := append([]scanner.CleanableItem(nil), items...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if ra, rb := rules.SafetyRank(a.SafeLevel), rules.SafetyRank(b.SafeLevel); ra != rb {
+			return ra < rb
+		}
+		if da, db := a.Age(now)/(24*time.Hour), b.Age(now)/(24*time.Hour); da != db {
+			return da > db
+		}
+		return a.DiskSize > b.DiskSize
+	})
+	return sorted
+}
+
+// PlanFreePercent returns the items, in SortForFreeing order, that
+// cleaning with Options.TargetFreePercent would remove given the current
+// free space: on each filesystem, items are taken until the projected free
+// space reaches percent. Items on filesystems whose free space can't be
+// read are all kept.
+func PlanFreePercent(items []scanner.CleanableItem, percent float64, now time.Time) []scanner.CleanableItem {
+	t := newFreeTarget(percent)
+	var chosen []scanner.CleanableItem
+	for _, item := range SortForFreeing(items, now) {
+		if t.skip(item) != "" {
+			continue
+		}
+		t.pending[item.Mount] += item.DiskSize
+		chosen = append(chosen, item)
+	}
+	return chosen
+}
+
+// freeTarget decides whether an item's filesystem already has the free
+// space Options.TargetFreePercent asks for
+type freeTarget struct {
+	percent float64
+	// pending is the space of items chosen but not removed yet, per mount
+	pending map[string]int64
+}
+
+func newFreeTarget(percent float64) *freeTarget {
+	return &freeTarget{percent: percent, pending: make(map[string]int64)}
+}
+
+// skip returns why item should be left alone, or "" to clean it. The free
+// space is read again every time, so it reflects items already removed.
+func (t *freeTarget) skip(item scanner.CleanableItem) string {
+	if item.Mount == "" {
+		return ""
+	}
+	fs, err := fsinfo.Stat(item.Mount)
+	if err != nil {
+		return ""
+	}
+	if free := fs.FreePercent(t.pending[item.Mount]); free >= t.percent {
+		return fmt.Sprintf("%s is %.1f%% free, target %g%% reached", item.Mount, free, t.percent)
+	}
+	return ""
+}
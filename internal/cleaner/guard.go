@@ -0,0 +1,145 @@
+package cleaner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/iml1s/antigravity-cleaner/internal/scanner"
+)
+
+// checkPath re-validates an item right before it is removed, since the
+// path comes from a scan that may be stale or, through a bad rule or an
+// unset HOME, wrong. It refuses paths that:
+//
+//   - aren't absolute, or are /, $HOME or a directory holding $HOME
+//   - aren't a path of the item's rule, or don't lie below the directory
+//     the item was found under
+//   - resolve outside that directory through a symlinked parent
+//   - have become a symlink, or no longer have the device and inode the
+//     scan saw
+func checkPath(item scanner.CleanableItem) error {
+	path := item.Path
+	if !filepath.IsAbs(path) {
+		return fmt.Errorf("refusing to clean %q: not an absolute path", path)
+	}
+	path = filepath.Clean(path)
+	if err := checkNotHome(path); err != nil {
+		return err
+	}
+
+	root, err := knownRoot(item)
+	if err != nil {
+		return err
+	}
+
+	// A rule path is its own root, so only this catches a rule item
+	// swapped for a link elsewhere
+	info, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSymlink != 0 && !item.Symlink {
+		return fmt.Errorf("refusing to clean %s: it became a symlink since the scan", path)
+	}
+
+	// The item itself may be a symlink, which is removed rather than
+	// followed, so only its parents are resolved
+	resolved, err := resolveParents(path)
+	if err != nil {
+		return fmt.Errorf("refusing to clean %s: %w", path, err)
+	}
+	if err := checkNotHome(resolved); err != nil {
+		return err
+	}
+	if root != path {
+		realRoot, err := filepath.EvalSymlinks(root)
+		if err != nil {
+			return fmt.Errorf("refusing to clean %s: %w", path, err)
+		}
+		if resolved == realRoot || !within(resolved, realRoot) {
+			return fmt.Errorf("refusing to clean %s: it resolves to %s, outside %s", path, resolved, realRoot)
+		}
+	}
+
+	if item.Dev != 0 || item.Ino != 0 {
+		if dev, ino := scanner.Identity(info); dev != item.Dev || ino != item.Ino {
+			return fmt.Errorf("refusing to clean %s: it was replaced since the scan", path)
+		}
+	}
+	return nil
+}
+
+// knownRoot returns the rule path the item is, or the directory it was
+// found under
+func knownRoot(item scanner.CleanableItem) (string, error) {
+	path := filepath.Clean(item.Path)
+	if item.Rule != "" {
+		for _, r := range scanner.ActiveRules() {
+			if r.ID != item.Rule {
+				continue
+			}
+			paths, _ := r.ExpandPaths(runtime.GOOS)
+			for _, p := range paths {
+				if p == path {
+					return path, nil
+				}
+			}
+			return "", fmt.Errorf("refusing to clean %s: not a path of rule %s", path, item.Rule)
+		}
+		return "", fmt.Errorf("refusing to clean %s: rule %s is not loaded", path, item.Rule)
+	}
+
+	root := filepath.Clean(item.Root)
+	if item.Root == "" || !filepath.IsAbs(root) {
+		return "", fmt.Errorf("refusing to clean %s: not found under a known root", path)
+	}
+	if path == root || !within(path, root) {
+		return "", fmt.Errorf("refusing to clean %s: not below %s, where it was found", path, root)
+	}
+	return root, nil
+}
+
+// checkNotHome refuses the filesystem root, the home directory and the
+// directories above it
+func checkNotHome(path string) error {
+	if path == filepath.Dir(path) {
+		return fmt.Errorf("refusing to clean %s: it is the filesystem root", path)
+	}
+	home, err := os.UserHomeDir()
+	if err != nil || home == "" {
+		return nil
+	}
+	homes := []string{filepath.Clean(home)}
+	if resolved, err := filepath.EvalSymlinks(home); err == nil {
+		homes = append(homes, resolved)
+	}
+	for _, h := range homes {
+		if within(h, path) {
+			return fmt.Errorf("refusing to clean %s: it is or holds the home directory", path)
+		}
+	}
+	return nil
+}
+
+// resolveParents resolves symlinks in every element of path but the last
+func resolveParents(path string) (string, error) {
+	parent, err := filepath.EvalSymlinks(filepath.Dir(path))
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(parent, filepath.Base(path)), nil
+}
+
+// within reports whether path is dir or lies below it
+func within(path, dir string) bool {
+	if path == dir {
+		return true
+	}
+	if !strings.HasSuffix(dir, string(filepath.Separator)) {
+		dir += string(filepath.Separator)
+	}
+	return strings.HasPrefix(path, dir)
+}
@@ -0,0 +1,156 @@
+//go:build !windows
+
+package cleaner
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/iml1s/antigravity-cleaner/internal/rules"
+	"github.com/iml1s/antigravity-cleaner/internal/scanner"
+)
+
+// scanned returns an item for path as a scan would have found it
+func scanned(t *testing.T, path, root string) scanner.CleanableItem {
+	t.Helper()
+	item := scanner.CleanableItem{Path: path, Root: root}
+	info, err := os.Lstat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	item.Dev, item.Ino = scanner.Identity(info)
+	item.Symlink = info.Mode()&os.ModeSymlink != 0
+	return item
+}
+
+func mkdirs(t *testing.T, paths ...string) {
+	t.Helper()
+	for _, path := range paths {
+		if err := os.MkdirAll(path, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCheckPath(t *testing.T) {
+	base := t.TempDir()
+	home := filepath.Join(base, "home")
+	projects := filepath.Join(home, "src")
+	outside := filepath.Join(base, "outside")
+	mkdirs(t, filepath.Join(projects, "app", "build"), filepath.Join(outside, "build"))
+	t.Setenv("HOME", home)
+
+	if err := os.Symlink(outside, filepath.Join(projects, "linked")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(projects, "app", "out")); err != nil {
+		t.Fatal(err)
+	}
+
+	build := filepath.Join(projects, "app", "build")
+	tests := []struct {
+		name string
+		item scanner.CleanableItem
+		want string
+	}{
+		{"below its root", scanned(t, build, projects), ""},
+		{"symlink seen by the scan", scanned(t, filepath.Join(projects, "app", "out"), projects), ""},
+		{"relative", scanner.CleanableItem{Path: "src/app/build", Root: projects}, "not an absolute path"},
+		{"filesystem root", scanner.CleanableItem{Path: "/", Root: "/"}, "filesystem root"},
+		{"home", scanner.CleanableItem{Path: home, Root: base}, "home directory"},
+		{"above home", scanner.CleanableItem{Path: base, Root: "/"}, "home directory"},
+		{"unclean home", scanner.CleanableItem{Path: home + "/src/..", Root: base}, "home directory"},
+		{"no root", scanner.CleanableItem{Path: build}, "not found under a known root"},
+		{"its own root", scanner.CleanableItem{Path: projects, Root: projects}, "not below"},
+		{"outside its root", scanner.CleanableItem{Path: filepath.Join(outside, "build"), Root: projects}, "not below"},
+		{"sibling prefix", scanner.CleanableItem{Path: projects + "2/build", Root: projects}, "not below"},
+		{"symlinked parent", scanned(t, filepath.Join(projects, "linked", "build"), projects), "resolves to"},
+		{"missing", scanner.CleanableItem{Path: filepath.Join(projects, "gone"), Root: projects}, "no such file"},
+	}
+	for _, tt := range tests {
+		err := checkPath(tt.item)
+		switch {
+		case tt.want == "" && err != nil:
+			t.Errorf("%s: %v", tt.name, err)
+		case tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)):
+			t.Errorf("%s: got %v, want an error containing %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestCheckPathReplaced(t *testing.T) {
+	base := t.TempDir()
+	t.Setenv("HOME", filepath.Join(base, "home"))
+	root := filepath.Join(base, "src")
+	build := filepath.Join(root, "app", "build")
+	mkdirs(t, build, filepath.Join(base, "elsewhere"))
+
+	item := scanned(t, build, root)
+	if err := os.Rename(build, build+".old"); err != nil {
+		t.Fatal(err)
+	}
+	mkdirs(t, build)
+	if err := checkPath(item); err == nil || !strings.Contains(err.Error(), "replaced") {
+		t.Errorf("replaced directory: got %v", err)
+	}
+
+	item = scanned(t, build, root)
+	if err := os.Remove(build); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(base, "elsewhere"), build); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkPath(item); err == nil || !strings.Contains(err.Error(), "became a symlink") {
+		t.Errorf("directory swapped for a symlink: got %v", err)
+	}
+}
+
+func TestCheckPathRules(t *testing.T) {
+	base := t.TempDir()
+	home := filepath.Join(base, "home")
+	cache := filepath.Join(home, ".cache", "guard-test")
+	mkdirs(t, cache, filepath.Join(base, "elsewhere"))
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CACHE_HOME", "")
+
+	scanner.UseRules(append(scanner.ActiveRules(), rules.Rule{
+		ID:          "guard-test",
+		Category:    "Guard Test",
+		Description: "test cache",
+		Safety:      "safe",
+		Paths:       map[string][]string{"all": {"${XDG_CACHE_HOME}/guard-test"}},
+	}))
+
+	item := scanned(t, cache, "")
+	item.Rule = "guard-test"
+	if err := checkPath(item); err != nil {
+		t.Errorf("rule path: %v", err)
+	}
+
+	other := item
+	other.Path = filepath.Join(cache, "sub")
+	if err := checkPath(other); err == nil || !strings.Contains(err.Error(), "not a path of rule") {
+		t.Errorf("path outside the rule: got %v", err)
+	}
+
+	other = item
+	other.Rule = "no-such-rule"
+	if err := checkPath(other); err == nil || !strings.Contains(err.Error(), "not loaded") {
+		t.Errorf("unknown rule: got %v", err)
+	}
+
+	// A rule path is its own root, so a link swapped in for it is caught
+	// by the symlink check alone
+	if err := os.Remove(cache); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(base, "elsewhere"), cache); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkPath(item); err == nil || !strings.Contains(err.Error(), "became a symlink") {
+		t.Errorf("rule path swapped for a symlink: got %v", err)
+	}
+}
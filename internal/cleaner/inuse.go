@@ -0,0 +1,44 @@
+package cleaner
+
+import (
+	"strings"
+
+	"github.com/iml1s/antigravity-cleaner/internal/procs"
+	"github.com/iml1s/antigravity-cleaner/internal/scanner"
+)
+
+// InUse is an item together with the running processes using it
+type InUse struct {
+	Item      scanner.CleanableItem
+	Processes []procs.Process
+}
+
+// FindInUse returns the items that one of their owning programs is running
+// for, or that a process has files open under. Providers with custom
+// removal logic look after their own items, so those aren't checked. Where
+// processes can't be listed, nothing is reported as in use.
+func FindInUse(items []scanner.CleanableItem) []InUse {
+	running, err := procs.List()
+	if err != nil {
+		return nil
+	}
+	var found []InUse
+	for _, item := range items {
+		if _, ok := scanner.Lookup(item.Category).(scanner.Cleaner); ok {
+			continue
+		}
+		if using := procs.Using(running, item.Path, item.Processes); len(using) > 0 {
+			found = append(found, InUse{Item: item, Processes: using})
+		}
+	}
+	return found
+}
+
+// inUseReason names the processes an item was skipped for
+func inUseReason(using []procs.Process) string {
+	names := make([]string, len(using))
+	for i, p := range using {
+		names[i] = p.String()
+	}
+	return "in use by " + strings.Join(names, ", ")
+}
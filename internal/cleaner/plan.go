@@ -0,0 +1,54 @@
+package cleaner
+
+import (
+	"time"
+
+	"github.com/iml1s/antigravity-cleaner/internal/rules"
+	"github.com/iml1s/antigravity-cleaner/internal/scanner"
+)
+
+// FreePlan is the set of items chosen to free a target amount of space
+type FreePlan struct {
+	Target int64
+	// Items are the chosen items in the order they were picked
+	Items []scanner.CleanableItem
+	// Bytes is the on-disk size of Items
+	Bytes int64
+	// Available is the on-disk size of every candidate, chosen or not
+	Available int64
+	// MaxSafety is the riskiest safety level among Items
+	MaxSafety string
+	// HeldBack is the on-disk size of the items above the HeldAbove safety
+	// level that an unattended run kept out of the candidates. PlanFree
+	// leaves both unset for the caller to fill in.
+	HeldBack  int64
+	HeldAbove string
+}
+
+// Met reports whether the plan reaches its target
+func (p FreePlan) Met() bool {
+	return p.Bytes >= p.Target
+}
+
+// PlanFree picks items in SortForFreeing order until their on-disk size
+// reaches target. If every candidate together is too small, the plan holds
+// all of them and Met is false.
+func PlanFree(items []scanner.CleanableItem, target int64, now time.Time) FreePlan {
+	candidates := SortForFreeing(items, now)
+
+	plan := FreePlan{Target: target}
+	for _, item := range candidates {
+		plan.Available += item.DiskSize
+	}
+	for _, item := range candidates {
+		if plan.Met() {
+			break
+		}
+		plan.Items = append(plan.Items, item)
+		plan.Bytes += item.DiskSize
+		if rules.SafetyRank(item.SafeLevel) > rules.SafetyRank(plan.MaxSafety) {
+			plan.MaxSafety = item.SafeLevel
+		}
+	}
+	return plan
+}
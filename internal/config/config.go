@@ -0,0 +1,314 @@
+// Package config reads the user configuration file,
+// $XDG_CONFIG_HOME/agc/config.yaml.
+package config
+
+import (
+	"bytes"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/iml1s/antigravity-cleaner/internal/profile"
+	"github.com/iml1s/antigravity-cleaner/internal/rules"
+	"github.com/iml1s/antigravity-cleaner/internal/units"
+	"github.com/iml1s/antigravity-cleaner/internal/xdg"
+	"gopkg.in/yaml.v3"
+)
+
+// Template is the commented starter file written by "agc config init"
+//
+//go:embed template.yaml
+var Template []byte
+
+// Config is the user configuration. Every field is optional.
+type Config struct {
+	// CustomPaths are extra directories to report alongside the rules
+	CustomPaths []CustomPath `yaml:"custom_paths,omitempty"`
+	// Exclude lists paths that are never reported, walked into, or
+	// cleaned. Entries starting with ~, $ or / are paths, which may contain
+	// globs; others are gitignore-style patterns matched at any depth.
+	Exclude []string `yaml:"exclude,omitempty"`
+	// ScanRoots are the directories searched for projects, replacing the
+	// default ~/Documents
+	ScanRoots []string `yaml:"scan_roots,omitempty"`
+	// DiscoverWorkspaces, unless false, adds the folders recently opened
+	// in Antigravity, VS Code and Cursor to the scan roots
+	DiscoverWorkspaces *bool `yaml:"discover_workspaces,omitempty"`
+	// Thresholds maps a category to the minimum size of items shown for
+	// it, e.g. {"Flutter": "500MiB"}, overriding the built-in minimums
+	Thresholds map[string]string `yaml:"thresholds,omitempty"`
+	// Defaults supplies values for global flags not given on the command
+	// line
+	Defaults Defaults `yaml:"defaults,omitempty"`
+	// Profiles defines named cleanup profiles, replacing built-in profiles
+	// of the same name
+	Profiles map[string]profile.Profile `yaml:"profiles,omitempty"`
+
+	// Path is the file the configuration was loaded from, if any
+	Path string `yaml:"-"`
+}
+
+// CustomPath is a user-defined cleanable directory. It becomes a rule, so
+// a category without a built-in provider gets its own subcommand.
+type CustomPath struct {
+	// ID names the rule; it defaults to custom-<n>
+	ID          string `yaml:"id,omitempty"`
+	Path        string `yaml:"path"`
+	Category    string `yaml:"category,omitempty"`
+	Description string `yaml:"description,omitempty"`
+	Safety      string `yaml:"safety,omitempty"`
+	MinSize     string `yaml:"min_size,omitempty"`
+}
+
+// Defaults holds default values for global flags
+type Defaults struct {
+	Jobs      int    `yaml:"jobs,omitempty"`
+	Timeout   string `yaml:"timeout,omitempty"`
+	MinSize   string `yaml:"min_size,omitempty"`
+	OlderThan string `yaml:"older_than,omitempty"`
+	NoCache   bool   `yaml:"no_cache,omitempty"`
+	Verbose   bool   `yaml:"verbose,omitempty"`
+}
+
+// DefaultPath returns the location of the configuration file
+func DefaultPath() string {
+	base := xdg.ConfigHome()
+	if base == "" {
+		return ""
+	}
+	return filepath.Join(base, "agc", "config.yaml")
+}
+
+// Load reads the configuration at path. A missing file yields an empty
+// configuration.
+func Load(path string) (*Config, error) {
+	cfg := &Config{}
+	if path == "" {
+		return cfg, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	cfg.Path = path
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Validate checks values that YAML decoding can't
+func (c *Config) Validate() error {
+	var errs []error
+	for _, r := range c.Rules() {
+		if err := r.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("custom_paths: %w", err))
+		}
+	}
+	for _, tmpl := range c.Exclude {
+		if isPathTemplate(tmpl) {
+			if _, err := rules.Expand(tmpl); err != nil {
+				errs = append(errs, fmt.Errorf("exclude: %w", err))
+			}
+		}
+	}
+	for _, tmpl := range c.ScanRoots {
+		if _, err := rules.Expand(tmpl); err != nil {
+			errs = append(errs, fmt.Errorf("scan_roots: %w", err))
+		}
+	}
+	for _, category := range sortedKeys(c.Thresholds) {
+		if _, err := humanize.ParseBytes(c.Thresholds[category]); err != nil {
+			errs = append(errs, fmt.Errorf("thresholds.%s: invalid size %q", category, c.Thresholds[category]))
+		}
+	}
+
+	d := c.Defaults
+	if d.Jobs < 0 {
+		errs = append(errs, fmt.Errorf("defaults.jobs: must not be negative, got %d", d.Jobs))
+	}
+	if d.Timeout != "" {
+		if t, err := time.ParseDuration(d.Timeout); err != nil || t < 0 {
+			errs = append(errs, fmt.Errorf("defaults.timeout: invalid duration %q", d.Timeout))
+		}
+	}
+	if d.MinSize != "" {
+		if _, err := humanize.ParseBytes(d.MinSize); err != nil {
+			errs = append(errs, fmt.Errorf("defaults.min_size: invalid size %q", d.MinSize))
+		}
+	}
+	if d.OlderThan != "" {
+		if _, err := units.ParseDuration(d.OlderThan); err != nil {
+			errs = append(errs, fmt.Errorf("defaults.older_than: %w", err))
+		}
+	}
+
+	for _, name := range profile.Names(c.Profiles) {
+		if err := c.Profiles[name].Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("profiles.%s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Rules converts the custom paths into rules
+func (c *Config) Rules() []rules.Rule {
+	source := c.Path
+	if source == "" {
+		source = "config"
+	}
+
+	result := make([]rules.Rule, len(c.CustomPaths))
+	for i, p := range c.CustomPaths {
+		r := rules.Rule{
+			ID:          p.ID,
+			Category:    p.Category,
+			Description: p.Description,
+			Safety:      p.Safety,
+			MinSize:     p.MinSize,
+			Paths:       map[string][]string{"all": {p.Path}},
+			Source:      source,
+		}
+		if p.Path == "" {
+			r.Paths = nil
+		}
+		if r.ID == "" {
+			r.ID = fmt.Sprintf("custom-%d", i+1)
+		}
+		if r.Category == "" {
+			r.Category = "Custom"
+		}
+		if r.Description == "" {
+			r.Description = filepath.Base(p.Path)
+		}
+		if r.Safety == "" {
+			r.Safety = "caution"
+		}
+		result[i] = r
+	}
+	return result
+}
+
+// AllProfiles returns the built-in profiles merged with the configured ones
+func (c *Config) AllProfiles() map[string]profile.Profile {
+	source := c.Path
+	if source == "" {
+		source = "config"
+	}
+	return profile.Merge(c.Profiles, source)
+}
+
+// ExcludePatterns returns the exclude list with path entries expanded.
+// Entries that fail to expand are left out; Validate reports them.
+func (c *Config) ExcludePatterns() []string {
+	var result []string
+	for _, tmpl := range c.Exclude {
+		if !isPathTemplate(tmpl) {
+			result = append(result, tmpl)
+			continue
+		}
+		if path, err := rules.Expand(tmpl); err == nil {
+			result = append(result, path)
+		}
+	}
+	return result
+}
+
+// isPathTemplate tells exclude entries naming a path apart from patterns
+func isPathTemplate(tmpl string) bool {
+	return strings.HasPrefix(tmpl, "~") || strings.HasPrefix(tmpl, "$") || filepath.IsAbs(tmpl) || strings.HasPrefix(tmpl, "/")
+}
+
+// Roots returns the expanded scan roots, or nil to use the default
+func (c *Config) Roots() []string {
+	return expandAll(c.ScanRoots)
+}
+
+// Discover reports whether recently opened editor workspaces are scanned
+func (c *Config) Discover() bool {
+	return c.DiscoverWorkspaces == nil || *c.DiscoverWorkspaces
+}
+
+// ThresholdBytes returns the per-category thresholds in bytes
+func (c *Config) ThresholdBytes() map[string]int64 {
+	result := make(map[string]int64, len(c.Thresholds))
+	for category, size := range c.Thresholds {
+		if n, err := humanize.ParseBytes(size); err == nil {
+			result[category] = int64(n)
+		}
+	}
+	return result
+}
+
+// Flags maps global flag names to the configured default values, leaving
+// out defaults that aren't set
+func (d Defaults) Flags() map[string]string {
+	flags := make(map[string]string)
+	if d.Jobs > 0 {
+		flags["jobs"] = strconv.Itoa(d.Jobs)
+	}
+	if d.Timeout != "" {
+		flags["timeout"] = d.Timeout
+	}
+	if d.MinSize != "" {
+		flags["min-size"] = d.MinSize
+	}
+	if d.OlderThan != "" {
+		flags["older-than"] = d.OlderThan
+	}
+	if d.NoCache {
+		flags["no-cache"] = "true"
+	}
+	if d.Verbose {
+		flags["verbose"] = "true"
+	}
+	return flags
+}
+
+// Marshal renders the configuration as YAML
+func (c *Config) Marshal() ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(c); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), enc.Close()
+}
+
+func expandAll(templates []string) []string {
+	var result []string
+	for _, tmpl := range templates {
+		if path, err := rules.Expand(tmpl); err == nil {
+			result = append(result, path)
+		}
+	}
+	return result
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,402 @@
+// Package filter implements the --select expression language, which picks
+// items by their fields:
+//
+//	category in (Flutter, Android) and safety == safe and size > 1GB and age > 14d
+//
+// Comparisons are joined with and, or and not, and grouped with
+// parentheses. Fields are category, safety (alias safe), size (on disk),
+// apparent, age, path, description and rule. Sizes take units such as MB
+// or GiB, ages take h, d, w, mo or y, and safety levels compare in the
+// order safe < caution < warning. String comparisons ignore case, and ~
+// matches a glob where * also crosses directory separators.
+package filter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/iml1s/antigravity-cleaner/internal/rules"
+	"github.com/iml1s/antigravity-cleaner/internal/scanner"
+	"github.com/iml1s/antigravity-cleaner/internal/units"
+)
+
+// Filter is a compiled expression
+type Filter struct {
+	source string
+	root   node
+}
+
+// Parse compiles an expression, returning a *SyntaxError that points at
+// the offending column on failure
+func Parse(expr string) (*Filter, error) {
+	tokens, err := lex(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{input: expr, tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, p.errorAt(tok, "unexpected %s", tok.describe())
+	}
+	return &Filter{source: expr, root: root}, nil
+}
+
+// String returns the expression the filter was compiled from
+func (f *Filter) String() string {
+	return f.source
+}
+
+// Match reports whether item satisfies the filter; now is used for ages
+func (f *Filter) Match(item scanner.CleanableItem, now time.Time) bool {
+	return f.root.eval(item, now)
+}
+
+// SyntaxError describes a malformed expression
+type SyntaxError struct {
+	Input string
+	// Pos is the byte offset of the problem in Input
+	Pos int
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%s at column %d\n  %s\n  %s^", e.Msg, e.Pos+1, e.Input, strings.Repeat(" ", e.Pos))
+}
+
+// fieldKind decides which values and operators a field accepts
+type fieldKind int
+
+const (
+	stringField fieldKind = iota
+	sizeField
+	ageField
+	safetyField
+)
+
+type field struct {
+	kind fieldKind
+	get  func(item scanner.CleanableItem, now time.Time) any
+}
+
+var fields = map[string]field{
+	"category":    {stringField, func(item scanner.CleanableItem, _ time.Time) any { return item.Category }},
+	"path":        {stringField, func(item scanner.CleanableItem, _ time.Time) any { return item.Path }},
+	"description": {stringField, func(item scanner.CleanableItem, _ time.Time) any { return item.Description }},
+	"rule":        {stringField, func(item scanner.CleanableItem, _ time.Time) any { return item.Rule }},
+	"safety":      {safetyField, func(item scanner.CleanableItem, _ time.Time) any { return int64(rules.SafetyRank(item.SafeLevel)) }},
+	"size":        {sizeField, func(item scanner.CleanableItem, _ time.Time) any { return item.DiskSize }},
+	"apparent":    {sizeField, func(item scanner.CleanableItem, _ time.Time) any { return item.Size }},
+	"age":         {ageField, func(item scanner.CleanableItem, now time.Time) any { return int64(item.Age(now)) }},
+}
+
+// fieldAliases maps alternative names to fields
+var fieldAliases = map[string]string{
+	"safe":  "safety",
+	"level": "safety",
+	"disk":  "size",
+}
+
+func lookupField(name string) (string, field, bool) {
+	name = strings.ToLower(name)
+	if alias, ok := fieldAliases[name]; ok {
+		name = alias
+	}
+	f, ok := fields[name]
+	return name, f, ok
+}
+
+func fieldNames() string {
+	return "category, safety, size, apparent, age, path, description, rule"
+}
+
+// node is a compiled boolean expression
+type node interface {
+	eval(item scanner.CleanableItem, now time.Time) bool
+}
+
+type andNode struct{ left, right node }
+type orNode struct{ left, right node }
+type notNode struct{ operand node }
+
+func (n andNode) eval(item scanner.CleanableItem, now time.Time) bool {
+	return n.left.eval(item, now) && n.right.eval(item, now)
+}
+
+func (n orNode) eval(item scanner.CleanableItem, now time.Time) bool {
+	return n.left.eval(item, now) || n.right.eval(item, now)
+}
+
+func (n notNode) eval(item scanner.CleanableItem, now time.Time) bool {
+	return !n.operand.eval(item, now)
+}
+
+// compareNode compares a field against one or more values. Values are
+// int64 for sizes, ages and safety ranks, and strings otherwise.
+type compareNode struct {
+	field  field
+	op     string
+	values []any
+	globs  []*regexp.Regexp
+}
+
+func (n compareNode) eval(item scanner.CleanableItem, now time.Time) bool {
+	got := n.field.get(item, now)
+	switch n.op {
+	case "in":
+		for _, v := range n.values {
+			if equal(got, v) {
+				return true
+			}
+		}
+		return false
+	case "not in":
+		for _, v := range n.values {
+			if equal(got, v) {
+				return false
+			}
+		}
+		return true
+	case "==":
+		return equal(got, n.values[0])
+	case "!=":
+		return !equal(got, n.values[0])
+	case "~":
+		return n.globs[0].MatchString(got.(string))
+	case "!~":
+		return !n.globs[0].MatchString(got.(string))
+	}
+
+	a, b := got.(int64), n.values[0].(int64)
+	switch n.op {
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	}
+	return false
+}
+
+func equal(a, b any) bool {
+	if s, ok := a.(string); ok {
+		return strings.EqualFold(s, b.(string))
+	}
+	return a == b
+}
+
+// globRegexp compiles a glob where * matches any run of characters,
+// including separators, and ? any single character
+func globRegexp(glob string) *regexp.Regexp {
+	var re strings.Builder
+	re.WriteString("(?i)^")
+	for _, c := range glob {
+		switch c {
+		case '*':
+			re.WriteString(".*")
+		case '?':
+			re.WriteString(".")
+		default:
+			re.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	re.WriteString("$")
+	return regexp.MustCompile(re.String())
+}
+
+// parser is a recursive-descent parser over the token list
+type parser struct {
+	input  string
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) errorAt(tok token, format string, args ...any) error {
+	return &SyntaxError{Input: p.input, Pos: tok.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().isKeyword("or") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().isKeyword("and") {
+		p.next()
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseNot() (node, error) {
+	if p.peek().isKeyword("not") {
+		p.next()
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	tok := p.next()
+	switch {
+	case tok.kind == tokLParen:
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokRParen {
+			return nil, p.errorAt(closing, "expected \")\" to close the group opened at column %d, got %s", tok.pos+1, closing.describe())
+		}
+		return inner, nil
+	case tok.kind == tokWord && !tok.isKeyword("and", "or", "not", "in"):
+		return p.parseComparison(tok)
+	}
+	return nil, p.errorAt(tok, "expected a field name (%s), got %s", fieldNames(), tok.describe())
+}
+
+func (p *parser) parseComparison(fieldTok token) (node, error) {
+	name, f, ok := lookupField(fieldTok.text)
+	if !ok {
+		return nil, p.errorAt(fieldTok, "unknown field %q (use one of %s)", fieldTok.text, fieldNames())
+	}
+
+	opTok := p.next()
+	op := opTok.text
+	switch {
+	case opTok.kind == tokOp:
+	case opTok.isKeyword("in"):
+		op = "in"
+	case opTok.isKeyword("not") && p.peek().isKeyword("in"):
+		p.next()
+		op = "not in"
+	default:
+		return nil, p.errorAt(opTok, "expected an operator (==, !=, >, >=, <, <=, ~, !~, in) after %q, got %s", fieldTok.text, opTok.describe())
+	}
+
+	n := compareNode{field: f, op: op}
+	if op == "in" || op == "not in" {
+		values, err := p.parseList(name, f)
+		if err != nil {
+			return nil, err
+		}
+		n.values = values
+		return n, nil
+	}
+
+	switch {
+	case (op == "~" || op == "!~") && f.kind != stringField:
+		return nil, p.errorAt(opTok, "%s only applies to category, path, description and rule, not %s", op, name)
+	case (op == ">" || op == ">=" || op == "<" || op == "<=") && f.kind == stringField:
+		return nil, p.errorAt(opTok, "%s can't be ordered; use ==, !=, ~ or in", name)
+	}
+
+	valueTok := p.next()
+	value, err := p.parseValue(name, f, valueTok)
+	if err != nil {
+		return nil, err
+	}
+	n.values = []any{value}
+	if op == "~" || op == "!~" {
+		n.globs = []*regexp.Regexp{globRegexp(value.(string))}
+	}
+	return n, nil
+}
+
+func (p *parser) parseList(name string, f field) ([]any, error) {
+	if open := p.next(); open.kind != tokLParen {
+		return nil, p.errorAt(open, "expected \"(\" to start the list after in, got %s", open.describe())
+	}
+	var values []any
+	for {
+		value, err := p.parseValue(name, f, p.next())
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+
+		sep := p.next()
+		switch sep.kind {
+		case tokComma:
+			continue
+		case tokRParen:
+			return values, nil
+		}
+		return nil, p.errorAt(sep, "expected \",\" or \")\" in the list, got %s", sep.describe())
+	}
+}
+
+// parseValue converts a value token to the type the field compares with
+func (p *parser) parseValue(name string, f field, tok token) (any, error) {
+	if tok.kind != tokWord && tok.kind != tokString {
+		return nil, p.errorAt(tok, "expected a value for %s, got %s", name, tok.describe())
+	}
+	switch f.kind {
+	case sizeField:
+		n, err := humanize.ParseBytes(tok.text)
+		if err != nil {
+			return nil, p.errorAt(tok, "invalid size %q for %s (use e.g. 500MB, 1GiB)", tok.text, name)
+		}
+		return int64(n), nil
+	case ageField:
+		d, err := units.ParseDuration(tok.text)
+		if err != nil {
+			return nil, p.errorAt(tok, "invalid age %q (use e.g. 12h, 14d, 2w, 6mo)", tok.text)
+		}
+		return int64(d), nil
+	case safetyField:
+		rank := rules.SafetyRank(strings.ToLower(tok.text))
+		if rank < 0 {
+			return nil, p.errorAt(tok, "invalid safety level %q (use one of %s)", tok.text, strings.Join(rules.SafetyLevels, ", "))
+		}
+		return int64(rank), nil
+	}
+	if name == "path" && strings.HasPrefix(tok.text, "~") {
+		if expanded, err := rules.Expand(tok.text); err == nil {
+			return expanded, nil
+		}
+	}
+	return tok.text, nil
+}
@@ -0,0 +1,119 @@
+package filter
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/iml1s/antigravity-cleaner/internal/scanner"
+)
+
+func TestMatch(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	item := scanner.CleanableItem{
+		Path:        "/home/dev/src/app/build",
+		Size:        3 << 30,
+		DiskSize:    2 << 30,
+		Category:    "Flutter",
+		Description: "Flutter build output",
+		SafeLevel:   "caution",
+		Rule:        "",
+		ModTime:     now.Add(-20 * 24 * time.Hour),
+	}
+
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{"category == flutter", true},
+		{"category = FLUTTER", true},
+		{"category != Flutter", false},
+		{"category in (Android, Flutter)", true},
+		{"category not in (Android, Xcode)", true},
+		{"safety == caution", true},
+		{"safe > safe", true},
+		{"safety <= safe", false},
+		{"level < warning", true},
+		{"size > 1GB", true},
+		{"size >= 2GiB", true},
+		{"size > 2GiB", false},
+		{"disk < 3GiB", true},
+		{"apparent == 3GiB", true},
+		{"age > 14d", true},
+		{"age > 3w", false},
+		{"age < 1mo", true},
+		{"path ~ */build", true},
+		{"path ~ /home/*/app/build", true},
+		{"path ~ '*/src/*/build'", true},
+		{"path !~ *.dart_tool*", true},
+		{"path ~ /home/dev/src/app/buil?", true},
+		{"description ~ '*build output'", true},
+		{"rule == ''", true},
+		{"category == Flutter and size > 1GB and age > 14d", true},
+		{"category == Android or size > 1GB", true},
+		{"category == Android or size > 10GB", false},
+		{"not category == Android", true},
+		{"not (category == Flutter and safety == caution)", false},
+		{"category == Android and size > 1GB or age > 14d", true},
+		{"category == Android and (size > 1GB or age > 14d)", false},
+		{"NOT category IN (android) AND safety == caution", true},
+	}
+	for _, tt := range tests {
+		f, err := Parse(tt.expr)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.expr, err)
+			continue
+		}
+		if got := f.Match(item, now); got != tt.want {
+			t.Errorf("%q matched %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		pos  int
+	}{
+		{"", 0},
+		{"colour == red", 0},
+		{"size >", 6},
+		{"size > big", 7},
+		{"age > 3 parsecs", 6},
+		{"safety == risky", 10},
+		{"category > Flutter", 9},
+		{"size ~ 1GB", 5},
+		{"category in Flutter", 12},
+		{"category in (Flutter Android)", 21},
+		{"(size > 1GB", 11},
+		{"size > 1GB)", 10},
+		{"category == 'Flutter", 12},
+		{"size > 1GB and", 14},
+		{"category Flutter", 9},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.expr)
+		var syntaxErr *SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			t.Errorf("Parse(%q): got %v, want a *SyntaxError", tt.expr, err)
+			continue
+		}
+		if syntaxErr.Pos != tt.pos {
+			t.Errorf("Parse(%q): error at %d, want %d: %v", tt.expr, syntaxErr.Pos, tt.pos, err)
+		}
+	}
+}
+
+func TestLexPaths(t *testing.T) {
+	tokens, err := lex("path ~ ~/src/*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var texts []string
+	for _, tok := range tokens[:len(tokens)-1] {
+		texts = append(texts, tok.text)
+	}
+	if len(texts) != 3 || texts[0] != "path" || texts[1] != "~" || texts[2] != "~/src/*" {
+		t.Errorf("lex split %q into %q", "path ~ ~/src/*", texts)
+	}
+}
@@ -0,0 +1,119 @@
+package filter
+
+import (
+	"fmt"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokWord
+	tokString
+	tokOp
+	tokLParen
+	tokRParen
+	tokComma
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	// pos is the byte offset of the token in the input
+	pos int
+}
+
+// isKeyword reports whether the token is an unquoted word equal to one of
+// words, ignoring case
+func (t token) isKeyword(words ...string) bool {
+	if t.kind != tokWord {
+		return false
+	}
+	for _, w := range words {
+		if strings.EqualFold(t.text, w) {
+			return true
+		}
+	}
+	return false
+}
+
+// describe names the token in error messages
+func (t token) describe() string {
+	if t.kind == tokEOF {
+		return "end of expression"
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+// operators are matched longest first
+var operators = []string{"==", "!=", ">=", "<=", "!~", ">", "<", "=", "~"}
+
+// lex splits an expression into tokens. Words run until whitespace or
+// punctuation, so values such as 1.5GB, 14d or ~/src need no quotes unless
+// they contain spaces, commas or parentheses.
+func lex(input string) ([]token, error) {
+	var tokens []token
+	i := 0
+	for i < len(input) {
+		c := input[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case c == '(':
+			tokens = append(tokens, token{tokLParen, "(", i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{tokRParen, ")", i})
+			i++
+		case c == ',':
+			tokens = append(tokens, token{tokComma, ",", i})
+			i++
+		case c == '"' || c == '\'':
+			end := strings.IndexByte(input[i+1:], c)
+			if end < 0 {
+				return nil, &SyntaxError{Input: input, Pos: i, Msg: "unterminated string"}
+			}
+			tokens = append(tokens, token{tokString, input[i+1 : i+1+end], i})
+			i += end + 2
+		default:
+			if op := matchOperator(input[i:]); op != "" {
+				// A lone = is accepted as ==
+				text := op
+				if op == "=" {
+					text = "=="
+				}
+				tokens = append(tokens, token{tokOp, text, i})
+				i += len(op)
+				continue
+			}
+			start := i
+			for i < len(input) && !strings.ContainsRune(" \t\n(),\"'=!<>", rune(input[i])) {
+				// ~ starts an operator only at the beginning of a word,
+				// so paths like ~/src stay whole
+				if input[i] == '~' && i == start && (i+1 == len(input) || input[i+1] != '/') {
+					break
+				}
+				i++
+			}
+			if i == start {
+				return nil, &SyntaxError{Input: input, Pos: i, Msg: fmt.Sprintf("unexpected character %q", c)}
+			}
+			tokens = append(tokens, token{tokWord, input[start:i], start})
+		}
+	}
+	return append(tokens, token{tokEOF, "", len(input)}), nil
+}
+
+func matchOperator(s string) string {
+	for _, op := range operators {
+		if strings.HasPrefix(s, op) {
+			// ~/ is the start of a path, not the match operator
+			if op == "~" && strings.HasPrefix(s, "~/") {
+				continue
+			}
+			return op
+		}
+	}
+	return ""
+}
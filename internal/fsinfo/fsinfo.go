@@ -0,0 +1,65 @@
+// Package fsinfo finds the filesystem a path lives on and how much space it
+// has left
+package fsinfo
+
+import "sync"
+
+// Filesystem describes a mounted filesystem
+type Filesystem struct {
+	// Mount is the directory the filesystem is mounted on, or the volume
+	// root on Windows
+	Mount string
+	// Type is the filesystem type, such as ext4 or apfs, when known
+	Type string
+	// Total is the size of the filesystem and Free the space available to
+	// unprivileged users, both in bytes
+	Total int64
+	Free  int64
+}
+
+// FreePercent returns the free space as a percentage of the total once
+// another extra bytes are freed
+func (f Filesystem) FreePercent(extra int64) float64 {
+	if f.Total <= 0 {
+		return 0
+	}
+	return float64(f.Free+extra) * 100 / float64(f.Total)
+}
+
+// Stat reads the current size and free space of the filesystem mounted at
+// mount
+func Stat(mount string) (Filesystem, error) {
+	fs, err := statfs(mount)
+	fs.Mount = mount
+	return fs, err
+}
+
+// Resolver finds the mount points of paths, remembering the answer for
+// each device so items on the same filesystem cost one lookup. It is safe
+// for concurrent use.
+type Resolver struct {
+	mu     sync.Mutex
+	mounts map[string]string
+}
+
+// NewResolver returns an empty Resolver
+func NewResolver() *Resolver {
+	return &Resolver{mounts: make(map[string]string)}
+}
+
+// Mount returns the mount point of the filesystem holding path, or "" if
+// path can't be read
+func (r *Resolver) Mount(path string) string {
+	dev, err := device(path)
+	if err != nil {
+		return ""
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if mount, ok := r.mounts[dev]; ok {
+		return mount
+	}
+	mount := mountPoint(path, dev)
+	r.mounts[dev] = mount
+	return mount
+}
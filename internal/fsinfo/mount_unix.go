@@ -0,0 +1,34 @@
+//go:build !windows
+
+package fsinfo
+
+import (
+	"path/filepath"
+	"strconv"
+	"syscall"
+)
+
+// device identifies the filesystem holding path
+func device(path string) (string, error) {
+	var st syscall.Stat_t
+	if err := syscall.Lstat(path, &st); err != nil {
+		return "", err
+	}
+	return strconv.FormatUint(uint64(st.Dev), 10), nil
+}
+
+// mountPoint walks up from path to the topmost directory still on dev,
+// which is where the filesystem is mounted
+func mountPoint(path, dev string) string {
+	path = filepath.Clean(path)
+	for {
+		parent := filepath.Dir(path)
+		if parent == path {
+			return path
+		}
+		if d, err := device(parent); err != nil || d != dev {
+			return path
+		}
+		path = parent
+	}
+}
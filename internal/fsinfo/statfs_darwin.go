@@ -0,0 +1,25 @@
+package fsinfo
+
+import (
+	"os"
+	"syscall"
+)
+
+func statfs(path string) (Filesystem, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return Filesystem{}, &os.PathError{Op: "statfs", Path: path, Err: err}
+	}
+	var name []byte
+	for _, c := range st.Fstypename {
+		if c == 0 {
+			break
+		}
+		name = append(name, byte(c))
+	}
+	return Filesystem{
+		Type:  string(name),
+		Total: int64(st.Blocks) * int64(st.Bsize),
+		Free:  int64(st.Bavail) * int64(st.Bsize),
+	}, nil
+}
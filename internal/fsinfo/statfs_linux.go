@@ -0,0 +1,75 @@
+package fsinfo
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+func statfs(path string) (Filesystem, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return Filesystem{}, &os.PathError{Op: "statfs", Path: path, Err: err}
+	}
+	// Block counts are in fragment-size units, which older kernels leave
+	// unset. The fields are int32 on some 32-bit platforms.
+	unit := int64(st.Frsize)
+	if unit == 0 {
+		unit = int64(st.Bsize)
+	}
+	return Filesystem{
+		Type:  mountType(path),
+		Total: int64(st.Blocks) * unit,
+		Free:  int64(st.Bavail) * unit,
+	}, nil
+}
+
+// mountType looks up the filesystem type of a mount point in
+// /proc/self/mountinfo. When several filesystems are stacked on the same
+// directory, the last one is visible.
+func mountType(mount string) string {
+	f, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	var fsType string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		// 36 35 98:0 /mnt1 /mnt2 rw,noatime master:1 - ext3 /dev/root rw
+		fields := strings.Fields(sc.Text())
+		if len(fields) < 5 || unescapeMount(fields[4]) != mount {
+			continue
+		}
+		for i, field := range fields {
+			if field == "-" && i+1 < len(fields) {
+				fsType = fields[i+1]
+				break
+			}
+		}
+	}
+	return fsType
+}
+
+// unescapeMount decodes the octal escapes mountinfo uses for spaces, tabs,
+// newlines and backslashes in paths
+func unescapeMount(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) {
+			if n, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(n))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,12 @@
+//go:build !linux && !darwin && !windows
+
+package fsinfo
+
+import (
+	"errors"
+	"os"
+)
+
+func statfs(path string) (Filesystem, error) {
+	return Filesystem{}, &os.PathError{Op: "statfs", Path: path, Err: errors.ErrUnsupported}
+}
@@ -0,0 +1,46 @@
+package fsinfo
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/windows"
+)
+
+// device identifies the volume holding path by its drive letter or UNC
+// share
+func device(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Lstat(abs); err != nil {
+		return "", err
+	}
+	return strings.ToUpper(filepath.VolumeName(abs)), nil
+}
+
+// mountPoint returns the root of the volume. Folders mounted inside other
+// volumes count towards the volume holding them.
+func mountPoint(path, dev string) string {
+	return dev + `\`
+}
+
+func statfs(path string) (Filesystem, error) {
+	dir, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return Filesystem{}, err
+	}
+	var free, total, totalFree uint64
+	if err := windows.GetDiskFreeSpaceEx(dir, &free, &total, &totalFree); err != nil {
+		return Filesystem{}, &os.PathError{Op: "statfs", Path: path, Err: err}
+	}
+
+	fs := Filesystem{Total: int64(total), Free: int64(free)}
+	var name [windows.MAX_PATH + 1]uint16
+	if windows.GetVolumeInformation(dir, nil, 0, nil, nil, nil, &name[0], uint32(len(name))) == nil {
+		fs.Type = windows.UTF16ToString(name[:])
+	}
+	return fs, nil
+}
@@ -0,0 +1,176 @@
+// Package history keeps the cleanup journal: an append-only JSON Lines
+// file with one record per item agc removed, quarantined, trashed,
+// restored or purged, or failed to.
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/iml1s/antigravity-cleaner/internal/cleaner"
+	"github.com/iml1s/antigravity-cleaner/internal/quarantine"
+	"github.com/iml1s/antigravity-cleaner/internal/xdg"
+)
+
+// Outcomes recorded besides the cleaner's
+const (
+	Restored = "restored"
+	Purged   = "purged"
+)
+
+// Record is one line of the journal
+type Record struct {
+	Time time.Time `json:"time"`
+	// Command is the agc command line that did it
+	Command     string `json:"command"`
+	Version     string `json:"version"`
+	Path        string `json:"path"`
+	Category    string `json:"category,omitempty"`
+	Description string `json:"description,omitempty"`
+	Safety      string `json:"safety,omitempty"`
+	Rule        string `json:"rule,omitempty"`
+	// Bytes is the item's on-disk size
+	Bytes int64 `json:"bytes"`
+	// Outcome is a cleaner outcome, Restored or Purged
+	Outcome      string `json:"outcome"`
+	QuarantineID string `json:"quarantine_id,omitempty"`
+	TrashPath    string `json:"trash_path,omitempty"`
+	Error        string `json:"error,omitempty"`
+}
+
+// Freed returns the bytes the record released: its size if the item was
+// removed or purged, and zero otherwise
+func (r Record) Freed() int64 {
+	if r.Outcome == string(cleaner.Removed) || r.Outcome == Purged {
+		return r.Bytes
+	}
+	return 0
+}
+
+// DefaultPath returns $XDG_STATE_HOME/agc/history.jsonl, or "" when no
+// home directory can be determined
+func DefaultPath() string {
+	dir := xdg.StateHome()
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, "agc", "history.jsonl")
+}
+
+// FromClean returns a record for each item of a clean run that was
+// attempted; skipped items were left alone and aren't recorded
+func FromClean(r cleaner.CleanReport, command, version string) []Record {
+	var records []Record
+	end := time.Now()
+	for _, result := range r.Items {
+		if result.Outcome == cleaner.Skipped {
+			continue
+		}
+		item := result.Item
+		rec := Record{
+			Time:         end,
+			Command:      command,
+			Version:      version,
+			Path:         item.Path,
+			Category:     item.Category,
+			Description:  item.Description,
+			Safety:       item.SafeLevel,
+			Rule:         item.Rule,
+			Bytes:        item.DiskSize,
+			Outcome:      string(result.Outcome),
+			QuarantineID: result.QuarantineID,
+			TrashPath:    result.TrashPath,
+		}
+		if result.Err != nil {
+			rec.Error = result.Err.Error()
+		}
+		records = append(records, rec)
+	}
+	return records
+}
+
+// FromQuarantine returns the record of restoring or purging a quarantined
+// item; outcome is Restored or Purged
+func FromQuarantine(e quarantine.Entry, outcome, command, version string) Record {
+	return Record{
+		Time:         time.Now(),
+		Command:      command,
+		Version:      version,
+		Path:         e.Path,
+		Category:     e.Category,
+		Description:  e.Description,
+		Safety:       e.Safety,
+		Rule:         e.Rule,
+		Bytes:        e.Size,
+		Outcome:      outcome,
+		QuarantineID: e.ID,
+	}
+}
+
+// Append adds records to the journal at path, creating it if needed. The
+// records go out in a single write to a file opened for appending, so
+// concurrent runs don't interleave their lines.
+func Append(path string, records []Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+	if path == "" {
+		return errors.New("no home directory for the history file")
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, rec := range records {
+		rec.Time = rec.Time.UTC()
+		if err := enc.Encode(rec); err != nil {
+			return err
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Read returns the records in the journal at path, oldest first. A missing
+// journal is empty. Lines that don't parse, such as one cut short by a
+// crash, are skipped and counted in bad.
+func Read(path string) (records []Record, bad int, err error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		line := bytes.TrimSpace(sc.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var rec Record
+		if err := json.Unmarshal(line, &rec); err != nil {
+			bad++
+			continue
+		}
+		records = append(records, rec)
+	}
+	return records, bad, sc.Err()
+}
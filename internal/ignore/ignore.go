@@ -0,0 +1,176 @@
+// Package ignore matches paths against gitignore-style patterns, as used by
+// .agcignore files and the exclude list in the configuration file.
+package ignore
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// FileName is the per-directory ignore file read by project walks
+const FileName = ".agcignore"
+
+// Matcher is an ordered list of patterns. As in gitignore, the last pattern
+// matching a path decides, and "!" patterns re-include paths.
+type Matcher struct {
+	patterns []pattern
+}
+
+type pattern struct {
+	raw     string
+	negate  bool
+	dirOnly bool
+	re      *regexp.Regexp
+}
+
+// New compiles patterns, skipping blank lines and # comments
+func New(lines []string) *Matcher {
+	m := &Matcher{}
+	for _, line := range lines {
+		if p, ok := compile(line); ok {
+			m.patterns = append(m.patterns, p)
+		}
+	}
+	return m
+}
+
+// Parse compiles the lines of an ignore file
+func Parse(data []byte) *Matcher {
+	var lines []string
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		lines = append(lines, sc.Text())
+	}
+	return New(lines)
+}
+
+// ReadFile compiles the ignore file at path
+func ReadFile(path string) (*Matcher, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data), nil
+}
+
+// Empty reports whether the matcher has no patterns
+func (m *Matcher) Empty() bool {
+	return m == nil || len(m.patterns) == 0
+}
+
+// Match reports whether rel, a slash-separated path relative to the
+// directory the patterns belong to, is excluded, and by which pattern. A
+// path inside an excluded directory is excluded too and can't be
+// re-included, as in gitignore.
+func (m *Matcher) Match(rel string, isDir bool) (string, bool) {
+	if m.Empty() {
+		return "", false
+	}
+	rel = strings.Trim(rel, "/")
+	if rel == "" || rel == "." {
+		return "", false
+	}
+
+	parts := strings.Split(rel, "/")
+	for i := 1; i <= len(parts); i++ {
+		dir := i < len(parts) || isDir
+		if raw, ok := m.matchOne(strings.Join(parts[:i], "/"), dir); ok {
+			return raw, true
+		}
+	}
+	return "", false
+}
+
+// matchOne applies the patterns to a single path, ignoring its parents
+func (m *Matcher) matchOne(path string, isDir bool) (string, bool) {
+	var raw string
+	excluded := false
+	for _, p := range m.patterns {
+		if p.dirOnly && !isDir {
+			continue
+		}
+		if p.re.MatchString(path) {
+			excluded = !p.negate
+			raw = p.raw
+		}
+	}
+	return raw, excluded
+}
+
+// compile turns one gitignore line into a regular expression
+func compile(line string) (pattern, bool) {
+	p := pattern{raw: line}
+
+	// Trailing spaces are ignored unless escaped
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, `\ `) {
+		line = line[:len(line)-1]
+	}
+	if line == "" || strings.HasPrefix(line, "#") {
+		return p, false
+	}
+	if strings.HasPrefix(line, "!") {
+		p.negate = true
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		p.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return p, false
+	}
+
+	// A slash anywhere but the end anchors the pattern to its directory;
+	// otherwise it matches a name at any depth
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+
+	var re strings.Builder
+	re.WriteString("^")
+	if !anchored {
+		re.WriteString("(?:.*/)?")
+	}
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case strings.HasPrefix(line[i:], "**/") && (i == 0 || line[i-1] == '/'):
+			re.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(line[i:], "**") && i+2 == len(line) && (i == 0 || line[i-1] == '/'):
+			re.WriteString(".*")
+			i++
+		case c == '*':
+			re.WriteString("[^/]*")
+		case c == '?':
+			re.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(line[i+1:], ']')
+			if end < 0 {
+				re.WriteString(`\[`)
+				continue
+			}
+			class := line[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			re.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(line):
+			i++
+			re.WriteString(regexp.QuoteMeta(string(line[i])))
+		default:
+			re.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	re.WriteString("$")
+
+	compiled, err := regexp.Compile(re.String())
+	if err != nil {
+		return p, false
+	}
+	p.re = compiled
+	return p, true
+}
@@ -0,0 +1,140 @@
+// Package procs finds running processes that may be using an item, either
+// because they are the application owning it or because they have files
+// open inside it.
+package procs
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Process is a running process
+type Process struct {
+	PID int
+	// Name is the kernel's name for the process, which may be truncated
+	Name string
+	// Exe is the executable's path, if it could be read
+	Exe  string
+	Args []string
+	// Files are the paths of its open files, if they could be read
+	Files []string
+}
+
+// String names the process for messages, e.g. "code (pid 4242)"
+func (p Process) String() string {
+	return fmt.Sprintf("%s (pid %d)", p.Label(), p.PID)
+}
+
+// Label returns the executable's base name, or Name when it's unknown
+func (p Process) Label() string {
+	if p.Exe != "" {
+		return filepath.Base(p.Exe)
+	}
+	return p.Name
+}
+
+// Is reports whether the process is the program called name, ignoring case
+// and any .exe suffix. The process name, executable and first argument are
+// compared. For a Java process, so is the last part of its main class, so
+// GradleDaemon matches the Java process running
+// org.gradle.launcher.daemon.bootstrap.GradleDaemon.
+func (p Process) Is(name string) bool {
+	candidates := []string{p.Name, filepath.Base(p.Exe)}
+	if len(p.Args) > 0 {
+		candidates = append(candidates, filepath.Base(p.Args[0]))
+	}
+	if p.isJava() {
+		if main := p.javaMain(); main != "" {
+			candidates = append(candidates, main)
+		}
+	}
+	for _, c := range candidates {
+		if strings.EqualFold(strings.TrimSuffix(c, ".exe"), name) {
+			return true
+		}
+	}
+	return false
+}
+
+// isJava reports whether the process is a Java virtual machine
+func (p Process) isJava() bool {
+	names := []string{p.Name, filepath.Base(p.Exe)}
+	if len(p.Args) > 0 {
+		names = append(names, filepath.Base(p.Args[0]))
+	}
+	for _, n := range names {
+		switch strings.ToLower(strings.TrimSuffix(n, ".exe")) {
+		case "java", "javaw":
+			return true
+		}
+	}
+	return false
+}
+
+// javaMain names what a Java command line runs: the simple name of the
+// main class, or of the class in -m module/class, or the base name of the
+// -jar file without .jar. It returns "" when there is none.
+func (p Process) javaMain() string {
+	for i := 1; i < len(p.Args); i++ {
+		arg := p.Args[i]
+		switch arg {
+		case "-cp", "-classpath", "--class-path", "-p", "--module-path":
+			// The next argument is a path list, not the main class
+			i++
+			continue
+		case "-jar", "-m", "--module":
+			if i+1 >= len(p.Args) {
+				return ""
+			}
+			next := p.Args[i+1]
+			if arg == "-jar" {
+				return strings.TrimSuffix(filepath.Base(next), ".jar")
+			}
+			if slash := strings.IndexByte(next, '/'); slash >= 0 {
+				return simpleName(next[slash+1:])
+			}
+			return ""
+		}
+		if !strings.HasPrefix(arg, "-") {
+			return simpleName(arg)
+		}
+	}
+	return ""
+}
+
+// simpleName drops the package from a Java class name
+func simpleName(class string) string {
+	return class[strings.LastIndexByte(class, '.')+1:]
+}
+
+// Uses reports whether the process has a file open at or below path
+func (p Process) Uses(path string) bool {
+	path = filepath.Clean(path)
+	prefix := path + string(filepath.Separator)
+	for _, f := range p.Files {
+		if f == path || strings.HasPrefix(f, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Using returns the processes among running that are one of owners or
+// have files open under path
+func Using(running []Process, path string, owners []string) []Process {
+	var found []Process
+	for _, p := range running {
+		if p.Uses(path) {
+			found = append(found, p)
+			continue
+		}
+		for _, owner := range owners {
+			if p.Is(owner) {
+				found = append(found, p)
+				break
+			}
+		}
+	}
+	return found
+}
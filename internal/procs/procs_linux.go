@@ -0,0 +1,64 @@
+package procs
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// List returns the processes in /proc, other than agc itself. Details of
+// processes belonging to other users are often unreadable and left empty.
+func List() ([]Process, error) {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil, err
+	}
+	self := os.Getpid()
+	var list []Process
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil || pid == self {
+			continue
+		}
+		dir := filepath.Join("/proc", entry.Name())
+		comm, err := os.ReadFile(filepath.Join(dir, "comm"))
+		if err != nil {
+			// Exited since the listing
+			continue
+		}
+		p := Process{PID: pid, Name: strings.TrimSpace(string(comm))}
+		if exe, err := os.Readlink(filepath.Join(dir, "exe")); err == nil {
+			p.Exe = strings.TrimSuffix(exe, " (deleted)")
+		}
+		if cmdline, err := os.ReadFile(filepath.Join(dir, "cmdline")); err == nil {
+			for _, arg := range bytes.Split(bytes.TrimRight(cmdline, "\x00"), []byte{0}) {
+				if len(arg) > 0 {
+					p.Args = append(p.Args, string(arg))
+				}
+			}
+		}
+		p.Files = openFiles(dir)
+		list = append(list, p)
+	}
+	return list, nil
+}
+
+// openFiles returns the paths the process in dir has open, leaving out
+// sockets, pipes and other descriptors that aren't files
+func openFiles(dir string) []string {
+	fds, err := os.ReadDir(filepath.Join(dir, "fd"))
+	if err != nil {
+		return nil
+	}
+	var files []string
+	for _, fd := range fds {
+		target, err := os.Readlink(filepath.Join(dir, "fd", fd.Name()))
+		if err != nil || !filepath.IsAbs(target) {
+			continue
+		}
+		files = append(files, strings.TrimSuffix(target, " (deleted)"))
+	}
+	return files
+}
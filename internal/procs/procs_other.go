@@ -0,0 +1,10 @@
+//go:build !linux
+
+package procs
+
+import "errors"
+
+// List is only implemented on Linux, where /proc describes each process
+func List() ([]Process, error) {
+	return nil, errors.ErrUnsupported
+}
@@ -0,0 +1,73 @@
+package procs
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestIs(t *testing.T) {
+	daemon := []string{"/usr/lib/jvm/bin/java", "-Xmx2g", "-cp", "/home/me/.gradle/wrapper/gradle-launcher.jar", "org.gradle.launcher.daemon.bootstrap.GradleDaemon", "8.5"}
+	tests := []struct {
+		name string
+		p    Process
+		want bool
+	}{
+		{"code", Process{Name: "code"}, true},
+		{"code", Process{Name: "Code.exe"}, true},
+		{"code", Process{Name: "code-tunnel"}, false},
+		{"studio", Process{Name: "java", Exe: "/opt/android-studio/bin/studio"}, true},
+		{"flutter", Process{Name: "bash", Args: []string{"/opt/flutter/bin/flutter", "run"}}, true},
+		{"GradleDaemon", Process{Name: "java", Exe: "/usr/lib/jvm/bin/java", Args: daemon}, true},
+		{"gradledaemon", Process{Name: "java", Args: daemon}, true},
+		{"GradleDaemon", Process{Name: "java", Args: []string{"java", "-classpath", "a.GradleDaemon", "org.example.Main"}}, false},
+		{"Main", Process{Name: "java", Args: []string{"java", "-m", "app/org.example.Main"}}, true},
+		{"kotlin-daemon", Process{Name: "java", Args: []string{"java", "-Dx=y", "-jar", "/opt/kotlin-daemon.jar"}}, true},
+		// Dotted arguments of other programs aren't class names
+		{"dart", Process{Name: "vim", Args: []string{"vim", "main.dart"}}, false},
+		{"gradle", Process{Name: "less", Args: []string{"less", "build.gradle"}}, false},
+		{"GradleDaemon", Process{Name: "grep", Args: []string{"grep", "-r", "org.gradle.launcher.daemon.bootstrap.GradleDaemon"}}, false},
+		{"java", Process{Name: "java", Args: []string{"java"}}, true},
+	}
+	for _, tt := range tests {
+		if got := tt.p.Is(tt.name); got != tt.want {
+			t.Errorf("%+v Is(%q) = %v, want %v", tt.p, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestUsing(t *testing.T) {
+	build := filepath.FromSlash("/p/app/build")
+	running := []Process{
+		{PID: 1, Name: "code"},
+		{PID: 2, Name: "vim", Args: []string{"vim", "main.dart"}, Files: []string{filepath.FromSlash("/p/app/lib/main.dart")}},
+		{PID: 3, Name: "gradle", Files: []string{filepath.FromSlash("/p/app/build/tmp/x.bin")}},
+		{PID: 4, Name: "cat", Files: []string{filepath.FromSlash("/p/app/build2/y")}},
+		{PID: 5, Name: "dart"},
+		{PID: 6, Name: "ls", Files: []string{build}},
+	}
+	tests := []struct {
+		owners []string
+		want   []int
+	}{
+		{nil, []int{3, 6}},
+		{[]string{"dart"}, []int{3, 5, 6}},
+		{[]string{"code", "dart"}, []int{1, 3, 5, 6}},
+	}
+	for _, tt := range tests {
+		got := Using(running, build, tt.owners)
+		var pids []int
+		for _, p := range got {
+			pids = append(pids, p.PID)
+		}
+		if len(pids) != len(tt.want) {
+			t.Errorf("owners %v: using %v, want %v", tt.owners, pids, tt.want)
+			continue
+		}
+		for i := range pids {
+			if pids[i] != tt.want[i] {
+				t.Errorf("owners %v: using %v, want %v", tt.owners, pids, tt.want)
+				break
+			}
+		}
+	}
+}
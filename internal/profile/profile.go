@@ -0,0 +1,201 @@
+// Package profile defines named cleanup profiles, which bundle the
+// category, safety, age and size filters and the strategy of a recurring
+// cleanup.
+package profile
+
+import (
+	"bytes"
+	_ "embed"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+	"github.com/iml1s/antigravity-cleaner/internal/filter"
+	"github.com/iml1s/antigravity-cleaner/internal/rules"
+	"github.com/iml1s/antigravity-cleaner/internal/units"
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed builtin.yaml
+var builtinYAML []byte
+
+// Strategies lists the accepted values of Profile.Strategy: the clean
+// --strategy values, or dry-run to only show what would be cleaned
+var Strategies = []string{"quarantine", "trash", "delete", "dry-run"}
+
+// Profile is a named set of cleanup settings. Empty fields leave the
+// corresponding flag at its default.
+type Profile struct {
+	Description string `yaml:"description,omitempty"`
+	// Categories limits the scan to these categories, like --only
+	Categories []string `yaml:"categories,omitempty"`
+	// Skip leaves these categories out, like --skip
+	Skip []string `yaml:"skip,omitempty"`
+	// MaxSafety is the riskiest safety level cleaned: safe, caution or
+	// warning
+	MaxSafety string `yaml:"max_safety,omitempty"`
+	// IncludeCaution and IncludeWarning let unattended runs clean caution
+	// or warning items, like --include-caution and --include-warning;
+	// MaxSafety still caps them
+	IncludeCaution bool `yaml:"include_caution,omitempty"`
+	IncludeWarning bool `yaml:"include_warning,omitempty"`
+
+	OlderThan string `yaml:"older_than,omitempty"`
+	MinSize   string `yaml:"min_size,omitempty"`
+	// Select is an expression items must match, on top of any --select
+	// given on the command line
+	Select string `yaml:"select,omitempty"`
+	// Strategy is how selected items are removed; see Strategies
+	Strategy string `yaml:"strategy,omitempty"`
+	// Prompt, when false, cleans every matching item without the
+	// interactive selection, like --all
+	Prompt *bool `yaml:"prompt,omitempty"`
+
+	// Name is the key the profile was defined under
+	Name string `yaml:"-"`
+	// Source is "built-in" or the config file defining the profile
+	Source string `yaml:"-"`
+}
+
+// Builtin returns the profiles shipped with agc
+func Builtin() map[string]Profile {
+	var profiles map[string]Profile
+	dec := yaml.NewDecoder(bytes.NewReader(builtinYAML))
+	dec.KnownFields(true)
+	if err := dec.Decode(&profiles); err != nil {
+		panic(fmt.Sprintf("profile: built-in profiles: %v", err))
+	}
+	for name, p := range profiles {
+		p.Name, p.Source = name, "built-in"
+		profiles[name] = p
+	}
+	return profiles
+}
+
+// Merge returns the built-in profiles overlaid with user profiles
+func Merge(user map[string]Profile, source string) map[string]Profile {
+	profiles := Builtin()
+	for name, p := range user {
+		p.Name, p.Source = name, source
+		profiles[name] = p
+	}
+	return profiles
+}
+
+// Names returns the profile names in alphabetical order
+func Names(profiles map[string]Profile) []string {
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Validate checks the profile's values
+func (p Profile) Validate() error {
+	var errs []error
+	if p.MaxSafety != "" && rules.SafetyRank(p.MaxSafety) < 0 {
+		errs = append(errs, fmt.Errorf("max_safety must be one of %s, got %q", strings.Join(rules.SafetyLevels, ", "), p.MaxSafety))
+	}
+	if p.MaxSafety != "" {
+		switch {
+		case p.IncludeWarning && rules.SafetyRank(p.MaxSafety) < rules.SafetyRank("warning"):
+			errs = append(errs, fmt.Errorf("include_warning is above max_safety %s", p.MaxSafety))
+		case p.IncludeCaution && rules.SafetyRank(p.MaxSafety) < rules.SafetyRank("caution"):
+			errs = append(errs, fmt.Errorf("include_caution is above max_safety %s", p.MaxSafety))
+		}
+	}
+	if p.OlderThan != "" {
+		if _, err := units.ParseDuration(p.OlderThan); err != nil {
+			errs = append(errs, fmt.Errorf("older_than: %w", err))
+		}
+	}
+	if p.MinSize != "" {
+		if _, err := humanize.ParseBytes(p.MinSize); err != nil {
+			errs = append(errs, fmt.Errorf("min_size: invalid size %q", p.MinSize))
+		}
+	}
+	if p.Select != "" {
+		if _, err := filter.Parse(p.Select); err != nil {
+			errs = append(errs, fmt.Errorf("select: %w", err))
+		}
+	}
+	if p.Strategy != "" && !contains(Strategies, p.Strategy) {
+		errs = append(errs, fmt.Errorf("strategy must be one of %s, got %q", strings.Join(Strategies, ", "), p.Strategy))
+	}
+	return errors.Join(errs...)
+}
+
+// Allows reports whether an item with the given safety level may be
+// cleaned under the profile
+func (p Profile) Allows(safety string) bool {
+	if p.MaxSafety == "" {
+		return true
+	}
+	return rules.SafetyRank(safety) <= rules.SafetyRank(p.MaxSafety)
+}
+
+// UnattendedLevel returns the riskiest safety level a run without the
+// interactive selection cleans under the profile: safe, unless the
+// --include-caution or --include-warning flags or the profile's own
+// include fields allow more, and never above MaxSafety
+func (p Profile) UnattendedLevel(includeCaution, includeWarning bool) string {
+	level := "safe"
+	switch {
+	case includeWarning || p.IncludeWarning:
+		level = "warning"
+	case includeCaution || p.IncludeCaution:
+		level = "caution"
+	}
+	if p.MaxSafety != "" && rules.SafetyRank(p.MaxSafety) < rules.SafetyRank(level) {
+		level = p.MaxSafety
+	}
+	return level
+}
+
+// Flags maps command flag names to the values the profile sets, leaving
+// out settings that aren't set
+func (p Profile) Flags() map[string]string {
+	flags := make(map[string]string)
+	if len(p.Categories) > 0 {
+		flags["only"] = strings.Join(p.Categories, ",")
+	}
+	if len(p.Skip) > 0 {
+		flags["skip"] = strings.Join(p.Skip, ",")
+	}
+	if p.OlderThan != "" {
+		flags["older-than"] = p.OlderThan
+	}
+	if p.MinSize != "" {
+		flags["min-size"] = p.MinSize
+	}
+	if p.IncludeCaution {
+		flags["include-caution"] = "true"
+	}
+	if p.IncludeWarning {
+		flags["include-warning"] = "true"
+	}
+	switch p.Strategy {
+	case "":
+	case "dry-run":
+		flags["dry-run"] = "true"
+	default:
+		flags["strategy"] = p.Strategy
+	}
+	if p.Prompt != nil && !*p.Prompt {
+		flags["all"] = "true"
+	}
+	return flags
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
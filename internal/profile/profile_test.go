@@ -0,0 +1,175 @@
+package profile
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/iml1s/antigravity-cleaner/internal/rules"
+)
+
+func TestBuiltin(t *testing.T) {
+	profiles := Builtin()
+	for _, name := range []string{"light", "deep", "ci-runner"} {
+		p, ok := profiles[name]
+		if !ok {
+			t.Errorf("built-in profile %s missing", name)
+			continue
+		}
+		if p.Name != name || p.Source != "built-in" {
+			t.Errorf("%s: name %q, source %q", name, p.Name, p.Source)
+		}
+		if err := p.Validate(); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+	profiles := Merge(map[string]Profile{
+		"deep":  {MaxSafety: "safe"},
+		"local": {Description: "mine"},
+	}, "/etc/agc.yaml")
+
+	if p := profiles["deep"]; p.MaxSafety != "safe" || p.OlderThan != "" || p.Source != "/etc/agc.yaml" {
+		t.Errorf("deep should be replaced whole, got %+v", p)
+	}
+	if p := profiles["local"]; p.Name != "local" || p.Source != "/etc/agc.yaml" {
+		t.Errorf("local: %+v", p)
+	}
+	if p := profiles["light"]; p.Source != "built-in" {
+		t.Errorf("light: %+v", p)
+	}
+	if got := strings.Join(Names(profiles), ","); got != "ci-runner,deep,light,local" {
+		t.Errorf("names %s", got)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	bad := Profile{
+		MaxSafety: "risky",
+		OlderThan: "soon",
+		MinSize:   "huge",
+		Select:    "size >",
+		Strategy:  "shred",
+	}
+	err := bad.Validate()
+	if err == nil {
+		t.Fatal("no error for an invalid profile")
+	}
+	for _, want := range []string{"max_safety", "older_than", "min_size", "select", "strategy"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q doesn't mention %s", err, want)
+		}
+	}
+
+	if err := (Profile{MaxSafety: "safe", IncludeCaution: true}).Validate(); err == nil || !strings.Contains(err.Error(), "include_caution") {
+		t.Errorf("include_caution above max_safety: got %v", err)
+	}
+
+	good := Profile{MaxSafety: "warning", OlderThan: "2w", MinSize: "1GB", Select: "category == Flutter", Strategy: "dry-run"}
+	if err := good.Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestAllows(t *testing.T) {
+	tests := []struct {
+		max, safety string
+		want        bool
+	}{
+		{"", "warning", true},
+		{"safe", "safe", true},
+		{"safe", "caution", false},
+		{"caution", "caution", true},
+		{"caution", "warning", false},
+		{"warning", "warning", true},
+	}
+	for _, tt := range tests {
+		if got := (Profile{MaxSafety: tt.max}).Allows(tt.safety); got != tt.want {
+			t.Errorf("max_safety %q allows %s = %v, want %v", tt.max, tt.safety, got, tt.want)
+		}
+	}
+}
+
+func TestFlags(t *testing.T) {
+	no := false
+	p := Profile{
+		Categories: []string{"Flutter", "Android"},
+		Skip:       []string{"Xcode"},
+		OlderThan:  "7d",
+		MinSize:    "100MB",
+		Select:     "size > 1GB",
+		Strategy:   "delete",
+		Prompt:     &no,
+	}
+	want := map[string]string{
+		"only":       "Flutter,Android",
+		"skip":       "Xcode",
+		"older-than": "7d",
+		"min-size":   "100MB",
+		"strategy":   "delete",
+		"all":        "true",
+	}
+	got := p.Flags()
+	if len(got) != len(want) {
+		t.Errorf("flags %v, want %v", got, want)
+	}
+	for name, value := range want {
+		if got[name] != value {
+			t.Errorf("flag %s = %q, want %q", name, got[name], value)
+		}
+	}
+
+	if got := (Profile{Strategy: "dry-run"}).Flags(); len(got) != 1 || got["dry-run"] != "true" {
+		t.Errorf("dry-run profile flags %v", got)
+	}
+	if got := (Profile{}).Flags(); len(got) != 0 {
+		t.Errorf("empty profile flags %v", got)
+	}
+}
+
+func TestUnattendedLevel(t *testing.T) {
+	builtin := Builtin()
+	tests := []struct {
+		profile                        Profile
+		includeCaution, includeWarning bool
+		want                           string
+	}{
+		{Profile{}, false, false, "safe"},
+		{Profile{}, true, false, "caution"},
+		{Profile{}, false, true, "warning"},
+		{builtin["light"], false, true, "safe"},
+		{builtin["deep"], false, false, "safe"},
+		{builtin["deep"], false, true, "caution"},
+		{builtin["ci-runner"], false, false, "caution"},
+		{builtin["ci-runner"], false, true, "caution"},
+		{Profile{IncludeWarning: true}, false, false, "warning"},
+	}
+	for _, tt := range tests {
+		if got := tt.profile.UnattendedLevel(tt.includeCaution, tt.includeWarning); got != tt.want {
+			t.Errorf("%q with caution=%v warning=%v: level %s, want %s", tt.profile.Name, tt.includeCaution, tt.includeWarning, got, tt.want)
+		}
+	}
+}
+
+// TestCIRunnerCleans checks which safety levels the unattended ci-runner
+// profile actually removes
+func TestCIRunnerCleans(t *testing.T) {
+	p := Builtin()["ci-runner"]
+	if p.Prompt == nil || *p.Prompt || p.Flags()["all"] != "true" {
+		t.Fatal("ci-runner should run without prompting")
+	}
+	if p.Flags()["include-caution"] != "true" {
+		t.Error("ci-runner doesn't set --include-caution")
+	}
+	level := p.UnattendedLevel(false, false)
+	for _, tt := range []struct {
+		safety string
+		want   bool
+	}{{"safe", true}, {"caution", true}, {"warning", false}} {
+		cleaned := p.Allows(tt.safety) && rules.SafetyRank(tt.safety) <= rules.SafetyRank(level)
+		if cleaned != tt.want {
+			t.Errorf("ci-runner cleans %s items: %v, want %v", tt.safety, cleaned, tt.want)
+		}
+	}
+}
@@ -0,0 +1,284 @@
+// Package quarantine keeps cleaned items aside on their own filesystem, with
+// a manifest recording where each came from, so they can be restored until
+// they are purged.
+package quarantine
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/iml1s/antigravity-cleaner/internal/fsinfo"
+	"github.com/iml1s/antigravity-cleaner/internal/scanner"
+	"github.com/iml1s/antigravity-cleaner/internal/xdg"
+)
+
+// manifestName is the file in the quarantine directory listing every entry,
+// including those kept on other filesystems
+const manifestName = "manifest.json"
+
+// Entry is one quarantined item
+type Entry struct {
+	ID string `json:"id"`
+	// Path is where the item was, and Stored where it is kept now
+	Path   string `json:"path"`
+	Stored string `json:"stored"`
+	// Size is the item's on-disk size, which purging it frees
+	Size        int64     `json:"size_bytes"`
+	Category    string    `json:"category"`
+	Description string    `json:"description"`
+	Safety      string    `json:"safety"`
+	Rule        string    `json:"rule,omitempty"`
+	Time        time.Time `json:"quarantined_at"`
+}
+
+// Store is the quarantine and its manifest. It isn't safe for concurrent
+// use.
+type Store struct {
+	dir     string
+	entries []Entry
+}
+
+// DefaultDir returns $XDG_DATA_HOME/agc/quarantine, or "" when no home
+// directory can be determined
+func DefaultDir() string {
+	dir := xdg.DataHome()
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, "agc", "quarantine")
+}
+
+// Open loads the quarantine in dir. A missing manifest means an empty
+// quarantine.
+func Open(dir string) (*Store, error) {
+	if dir == "" {
+		return nil, errors.New("no home directory for the quarantine")
+	}
+	s := &Store{dir: dir}
+	data, err := os.ReadFile(filepath.Join(dir, manifestName))
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &s.entries); err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Join(dir, manifestName), err)
+	}
+	return s, nil
+}
+
+// Entries returns the quarantined items, oldest first
+func (s *Store) Entries() []Entry {
+	entries := append([]Entry(nil), s.entries...)
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Time.Before(entries[j].Time) })
+	return entries
+}
+
+// Add moves item into the quarantine and records it. The item is kept on
+// its own filesystem: in the quarantine directory when that is on the same
+// filesystem, and otherwise in a .agc-quarantine directory at the top of
+// the item's mount.
+func (s *Store) Add(item scanner.CleanableItem) (Entry, error) {
+	dir, err := s.dirFor(item)
+	if err != nil {
+		return Entry{}, err
+	}
+	id, holder, err := s.newHolder(dir)
+	if err != nil {
+		return Entry{}, err
+	}
+	stored := filepath.Join(holder, filepath.Base(item.Path))
+	if err := os.Rename(item.Path, stored); err != nil {
+		os.Remove(holder)
+		return Entry{}, err
+	}
+
+	entry := Entry{
+		ID:          id,
+		Path:        item.Path,
+		Stored:      stored,
+		Size:        item.DiskSize,
+		Category:    item.Category,
+		Description: item.Description,
+		Safety:      item.SafeLevel,
+		Rule:        item.Rule,
+		Time:        time.Now().UTC(),
+	}
+	s.entries = append(s.entries, entry)
+	if err := s.save(); err != nil {
+		// Without a manifest entry the item couldn't be found again
+		os.Rename(stored, item.Path)
+		os.Remove(holder)
+		s.entries = s.entries[:len(s.entries)-1]
+		return Entry{}, err
+	}
+	return entry, nil
+}
+
+// dirFor returns the quarantine directory on item's filesystem
+func (s *Store) dirFor(item scanner.CleanableItem) (string, error) {
+	if err := os.MkdirAll(s.dir, 0o700); err != nil {
+		return "", err
+	}
+	if item.Mount == "" || item.Mount == fsinfo.NewResolver().Mount(s.dir) {
+		return s.dir, nil
+	}
+	name := ".agc-quarantine"
+	if uid := os.Getuid(); uid >= 0 {
+		name += "-" + strconv.Itoa(uid)
+	}
+	dir := filepath.Join(item.Mount, name)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", fmt.Errorf("no quarantine on %s: %w", item.Mount, err)
+	}
+	return dir, nil
+}
+
+// Find returns the entry with the given ID or, failing that, the newest
+// entry quarantined from the given path
+func (s *Store) Find(ref string) (Entry, bool) {
+	if e, ok := s.byID(ref); ok {
+		return e, true
+	}
+	path, err := filepath.Abs(ref)
+	if err != nil {
+		return Entry{}, false
+	}
+	var found Entry
+	for _, e := range s.entries {
+		if e.Path == path && (found.ID == "" || e.Time.After(found.Time)) {
+			found = e
+		}
+	}
+	return found, found.ID != ""
+}
+
+// Restore moves an entry back to its original path, which must not exist
+// again in the meantime
+func (s *Store) Restore(ref string) (Entry, error) {
+	entry, ok := s.Find(ref)
+	if !ok {
+		return Entry{}, fmt.Errorf("%s is not in the quarantine (see 'agc quarantine list')", ref)
+	}
+	if _, err := os.Lstat(entry.Path); err == nil {
+		return entry, fmt.Errorf("%s already exists; move it away first", entry.Path)
+	}
+	if err := os.MkdirAll(filepath.Dir(entry.Path), 0o755); err != nil {
+		return entry, err
+	}
+	if err := os.Rename(entry.Stored, entry.Path); err != nil {
+		return entry, err
+	}
+	os.Remove(filepath.Dir(entry.Stored))
+	s.remove(entry.ID)
+	return entry, s.save()
+}
+
+// Purge deletes the entries quarantined longer than olderThan ago, or
+// every entry when olderThan is zero, and returns them. Entries that
+// couldn't be deleted stay in the manifest and are reported in the error.
+func (s *Store) Purge(olderThan time.Duration, now time.Time) ([]Entry, error) {
+	var purged []Entry
+	var errs []error
+	for _, e := range s.Entries() {
+		if olderThan > 0 && now.Sub(e.Time) < olderThan {
+			continue
+		}
+		holder := filepath.Dir(e.Stored)
+		// A damaged manifest must never point the purge elsewhere
+		if filepath.Base(holder) != e.ID {
+			errs = append(errs, fmt.Errorf("%s: stored path %s is outside its quarantine directory", e.ID, e.Stored))
+			continue
+		}
+		if err := os.RemoveAll(holder); err != nil {
+			errs = append(errs, fmt.Errorf("%s (%s): %w", e.ID, e.Path, err))
+			continue
+		}
+		s.remove(e.ID)
+		purged = append(purged, e)
+	}
+	if len(purged) > 0 {
+		if err := s.save(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return purged, errors.Join(errs...)
+}
+
+func (s *Store) remove(id string) {
+	for i, e := range s.entries {
+		if e.ID == id {
+			s.entries = append(s.entries[:i], s.entries[i+1:]...)
+			return
+		}
+	}
+}
+
+// save writes the manifest atomically, so a crash never leaves it half
+// written
+func (s *Store) save() error {
+	entries := s.entries
+	if entries == nil {
+		entries = []Entry{}
+	}
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(s.dir, manifestName+".tmp")
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(s.dir, manifestName))
+}
+
+// newHolder creates the directory an item is moved into, named by a new
+// entry ID. Each holder must belong to exactly one entry, so an ID that is
+// already in the manifest or on disk is never reused.
+func (s *Store) newHolder(dir string) (id, holder string, err error) {
+	for attempt := 0; attempt < 10; attempt++ {
+		if id, err = newID(); err != nil {
+			return "", "", err
+		}
+		if _, taken := s.byID(id); taken {
+			continue
+		}
+		holder = filepath.Join(dir, id)
+		err = os.Mkdir(holder, 0o700)
+		if errors.Is(err, fs.ErrExist) {
+			continue
+		}
+		return id, holder, err
+	}
+	return "", "", fmt.Errorf("could not pick a free quarantine ID in %s", dir)
+}
+
+// byID returns the entry with the given ID
+func (s *Store) byID(id string) (Entry, bool) {
+	for _, e := range s.entries {
+		if e.ID == id {
+			return e, true
+		}
+	}
+	return Entry{}, false
+}
+
+// newID returns a sortable, unique entry ID such as
+// 20240131-154502-9f3a0c2d71b4e856
+func newID() (string, error) {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return time.Now().Format("20060102-150405") + "-" + hex.EncodeToString(b[:]), nil
+}
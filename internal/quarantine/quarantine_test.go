@@ -0,0 +1,192 @@
+package quarantine
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iml1s/antigravity-cleaner/internal/scanner"
+)
+
+// makeItem creates a directory holding one file and returns it as an item
+func makeItem(t *testing.T, path string) scanner.CleanableItem {
+	t.Helper()
+	if err := os.MkdirAll(path, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(path, "data"), []byte(path), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return scanner.CleanableItem{Path: path, DiskSize: 4096, Category: "Flutter", SafeLevel: "safe"}
+}
+
+func TestAddRestore(t *testing.T) {
+	base := t.TempDir()
+	dir := filepath.Join(base, "quarantine")
+	s, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	build := filepath.Join(base, "app", "build")
+	entry, err := s.Add(makeItem(t, build))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Lstat(build); !os.IsNotExist(err) {
+		t.Errorf("%s still exists after Add: %v", build, err)
+	}
+	if filepath.Dir(filepath.Dir(entry.Stored)) != dir || filepath.Base(filepath.Dir(entry.Stored)) != entry.ID {
+		t.Errorf("stored at %s, want %s/%s/build", entry.Stored, dir, entry.ID)
+	}
+	if data, err := os.ReadFile(filepath.Join(entry.Stored, "data")); err != nil || string(data) != build {
+		t.Errorf("stored contents %q, %v", data, err)
+	}
+
+	// The manifest survives reopening
+	s, err = Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Entries(); len(got) != 1 || got[0].ID != entry.ID || got[0].Path != build || got[0].Size != 4096 {
+		t.Fatalf("reopened entries %+v", got)
+	}
+
+	// Restoring refuses to overwrite a new item at the same path
+	makeItem(t, build)
+	if _, err := s.Restore(entry.ID); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("restore over an existing path: got %v", err)
+	}
+	if err := os.RemoveAll(build); err != nil {
+		t.Fatal(err)
+	}
+
+	// An entry can be restored by the path it came from
+	restored, err := s.Restore(build)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.ID != entry.ID {
+		t.Errorf("restored %s, want %s", restored.ID, entry.ID)
+	}
+	if data, err := os.ReadFile(filepath.Join(build, "data")); err != nil || string(data) != build {
+		t.Errorf("restored contents %q, %v", data, err)
+	}
+	if _, err := os.Lstat(filepath.Dir(entry.Stored)); !os.IsNotExist(err) {
+		t.Errorf("holder left behind: %v", err)
+	}
+	if len(s.Entries()) != 0 {
+		t.Errorf("entries left after restore: %+v", s.Entries())
+	}
+	if _, err := s.Restore(entry.ID); err == nil {
+		t.Error("restored the same entry twice")
+	}
+}
+
+func TestFindNewest(t *testing.T) {
+	base := t.TempDir()
+	s, err := Open(filepath.Join(base, "quarantine"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	build := filepath.Join(base, "build")
+	first, err := s.Add(makeItem(t, build))
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := s.Add(makeItem(t, build))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Make the second entry clearly the newer one
+	s.entries[0].Time = s.entries[1].Time.Add(-time.Minute)
+
+	if e, ok := s.Find(build); !ok || e.ID != second.ID {
+		t.Errorf("Find(%s) = %s, want the newer %s", build, e.ID, second.ID)
+	}
+	if e, ok := s.Find(first.ID); !ok || e.ID != first.ID {
+		t.Errorf("Find(%s) = %s, %v", first.ID, e.ID, ok)
+	}
+	if _, ok := s.Find(filepath.Join(base, "other")); ok {
+		t.Error("found an entry for a path never quarantined")
+	}
+}
+
+func TestUniqueIDs(t *testing.T) {
+	base := t.TempDir()
+	s, err := Open(filepath.Join(base, "quarantine"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		e, err := s.Add(makeItem(t, filepath.Join(base, "build")))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ids[e.ID] {
+			t.Fatalf("ID %s issued twice", e.ID)
+		}
+		ids[e.ID] = true
+
+		// Each holder keeps exactly the one item moved into it
+		names, err := os.ReadDir(filepath.Dir(e.Stored))
+		if err != nil || len(names) != 1 {
+			t.Fatalf("holder of %s holds %v, %v", e.ID, names, err)
+		}
+	}
+}
+
+func TestPurge(t *testing.T) {
+	base := t.TempDir()
+	s, err := Open(filepath.Join(base, "quarantine"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	var entries []Entry
+	for i, age := range []time.Duration{40 * 24 * time.Hour, 10 * 24 * time.Hour, time.Hour} {
+		e, err := s.Add(makeItem(t, filepath.Join(base, "build"+string(rune('a'+i)))))
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.entries[i].Time = now.Add(-age)
+		entries = append(entries, e)
+	}
+
+	purged, err := s.Purge(30*24*time.Hour, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(purged) != 1 || purged[0].ID != entries[0].ID {
+		t.Fatalf("purged %+v, want only the 40 day old entry", purged)
+	}
+	if _, err := os.Lstat(filepath.Dir(entries[0].Stored)); !os.IsNotExist(err) {
+		t.Errorf("purged holder still exists: %v", err)
+	}
+
+	// A damaged manifest mustn't point the purge outside the quarantine
+	victim := filepath.Join(base, "keep")
+	makeItem(t, victim)
+	s.entries[0].Stored = filepath.Join(victim, "data")
+	purged, err = s.Purge(0, now)
+	if err == nil || !strings.Contains(err.Error(), "outside its quarantine directory") {
+		t.Errorf("damaged entry: got %v", err)
+	}
+	if len(purged) != 1 || purged[0].ID != entries[2].ID {
+		t.Errorf("purged %+v, want only the undamaged entry", purged)
+	}
+	if _, err := os.Lstat(filepath.Join(victim, "data")); err != nil {
+		t.Errorf("purge removed a path outside the quarantine: %v", err)
+	}
+
+	reopened, err := Open(s.dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := reopened.Entries(); len(got) != 1 || got[0].ID != entries[1].ID {
+		t.Errorf("manifest after purging holds %+v", got)
+	}
+}
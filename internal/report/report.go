@@ -0,0 +1,467 @@
+// Package report renders scan and clean results in machine-readable
+// formats. The schema is versioned by SchemaVersion and documented in the
+// README; fields are only added within a version, never renamed or removed.
+package report
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/iml1s/antigravity-cleaner/internal/cleaner"
+	"github.com/iml1s/antigravity-cleaner/internal/scanner"
+	"gopkg.in/yaml.v3"
+)
+
+// SchemaVersion is bumped whenever a field changes meaning or is removed
+const SchemaVersion = 1
+
+// Formats lists the accepted values of --output; "text" is the default
+// human-readable output
+var Formats = []string{"text", "json", "yaml", "csv", "ndjson"}
+
+// Item is one cleanable item
+type Item struct {
+	Path        string `json:"path" yaml:"path"`
+	Category    string `json:"category" yaml:"category"`
+	Description string `json:"description" yaml:"description"`
+	Safety      string `json:"safety" yaml:"safety"`
+	Rule        string `json:"rule,omitempty" yaml:"rule,omitempty"`
+	// SizeBytes is the apparent size, DiskBytes the space allocated on disk
+	SizeBytes int64 `json:"size_bytes" yaml:"size_bytes"`
+	DiskBytes int64 `json:"disk_bytes" yaml:"disk_bytes"`
+	// Incomplete means the sizes are lower bounds
+	Incomplete bool `json:"incomplete" yaml:"incomplete"`
+	// LastUsed is the newest modification or access time inside the item,
+	// and AgeSeconds how long ago that was; both are omitted when unknown
+	LastUsed   *time.Time `json:"last_used,omitempty" yaml:"last_used,omitempty"`
+	AgeSeconds *int64     `json:"age_seconds,omitempty" yaml:"age_seconds,omitempty"`
+	// Mount is the mount point of the filesystem holding the item
+	Mount  string  `json:"mount,omitempty" yaml:"mount,omitempty"`
+	Errors []Error `json:"errors,omitempty" yaml:"errors,omitempty"`
+}
+
+// Error is a path that couldn't be read
+type Error struct {
+	Provider string `json:"provider,omitempty" yaml:"provider,omitempty"`
+	Path     string `json:"path" yaml:"path"`
+	Error    string `json:"error" yaml:"error"`
+}
+
+// Exclusion is a path left out by an exclude pattern or .agcignore file
+type Exclusion struct {
+	Path   string `json:"path" yaml:"path"`
+	Reason string `json:"reason" yaml:"reason"`
+}
+
+// Filesystem is the space items take up on one filesystem
+type Filesystem struct {
+	Mount string `json:"mount" yaml:"mount"`
+	Type  string `json:"type,omitempty" yaml:"type,omitempty"`
+	// TotalBytes and FreeBytes are the size and current free space, both
+	// omitted when they couldn't be read
+	TotalBytes *int64 `json:"total_bytes,omitempty" yaml:"total_bytes,omitempty"`
+	FreeBytes  *int64 `json:"free_bytes,omitempty" yaml:"free_bytes,omitempty"`
+	// Items and DiskBytes count the items on the filesystem, and
+	// FreeAfterBytes is the free space projected once they are removed
+	Items          int    `json:"items" yaml:"items"`
+	DiskBytes      int64  `json:"disk_bytes" yaml:"disk_bytes"`
+	FreeAfterBytes *int64 `json:"free_after_bytes,omitempty" yaml:"free_after_bytes,omitempty"`
+	Error          string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// Summary holds the totals of a scan
+type Summary struct {
+	Items     int   `json:"items" yaml:"items"`
+	SizeBytes int64 `json:"size_bytes" yaml:"size_bytes"`
+	DiskBytes int64 `json:"disk_bytes" yaml:"disk_bytes"`
+	// HiddenItems were below their size threshold, RecentItems were used
+	// more recently than --older-than allows
+	HiddenItems int   `json:"hidden_items" yaml:"hidden_items"`
+	HiddenBytes int64 `json:"hidden_bytes" yaml:"hidden_bytes"`
+	RecentItems int   `json:"recent_items" yaml:"recent_items"`
+	RecentBytes int64 `json:"recent_bytes" yaml:"recent_bytes"`
+	// Incomplete is set when the scan timed out or was interrupted, with
+	// the reason in Status: "complete", "timed_out" or "interrupted"
+	Incomplete bool   `json:"incomplete" yaml:"incomplete"`
+	Status     string `json:"status" yaml:"status"`
+}
+
+// Document is the whole output of --output json or yaml
+type Document struct {
+	SchemaVersion int `json:"schema_version" yaml:"schema_version"`
+	// Kind is "scan" or "dry-run"
+	Kind        string       `json:"kind" yaml:"kind"`
+	GeneratedAt time.Time    `json:"generated_at" yaml:"generated_at"`
+	Items       []Item       `json:"items" yaml:"items"`
+	Summary     Summary      `json:"summary" yaml:"summary"`
+	Filesystems []Filesystem `json:"filesystems,omitempty" yaml:"filesystems,omitempty"`
+	Excluded    []Exclusion  `json:"excluded,omitempty" yaml:"excluded,omitempty"`
+	Errors      []Error      `json:"errors,omitempty" yaml:"errors,omitempty"`
+}
+
+// Line is one line of --output ndjson: an item as soon as it is found, then
+// a single summary once the scan ends
+type Line struct {
+	SchemaVersion int `json:"schema_version"`
+	// Type is "item" or "summary"
+	Type        string       `json:"type"`
+	Item        *Item        `json:"item,omitempty"`
+	Summary     *Summary     `json:"summary,omitempty"`
+	Filesystems []Filesystem `json:"filesystems,omitempty"`
+	Excluded    []Exclusion  `json:"excluded,omitempty"`
+	Errors      []Error      `json:"errors,omitempty"`
+}
+
+// NewItem converts a scanned item, computing its age relative to now
+func NewItem(item scanner.CleanableItem, now time.Time) Item {
+	result := Item{
+		Path:        item.Path,
+		Category:    item.Category,
+		Description: item.Description,
+		Safety:      item.SafeLevel,
+		Rule:        item.Rule,
+		SizeBytes:   item.Size,
+		DiskBytes:   item.DiskSize,
+		Incomplete:  item.Incomplete,
+		Mount:       item.Mount,
+		Errors:      newErrors(item.Errors),
+	}
+	if lastUsed := item.LastUsed(); !lastUsed.IsZero() {
+		t := lastUsed.UTC()
+		result.LastUsed = &t
+		age := int64(item.Age(now) / time.Second)
+		result.AgeSeconds = &age
+	}
+	return result
+}
+
+// NewDocument converts a scan result. kind is "scan" or "dry-run"; for a
+// dry run, items are the selected subset of result.Items.
+func NewDocument(kind string, result scanner.Result, items []scanner.CleanableItem) Document {
+	now := time.Now()
+	doc := Document{
+		SchemaVersion: SchemaVersion,
+		Kind:          kind,
+		GeneratedAt:   now.UTC(),
+		Items:         make([]Item, 0, len(items)),
+		Summary:       newSummary(result, items),
+		Filesystems:   newFilesystems(items),
+		Excluded:      newExclusions(result.Excluded),
+		Errors:        newErrors(result.Errors),
+	}
+	for _, item := range items {
+		doc.Items = append(doc.Items, NewItem(item, now))
+	}
+	return doc
+}
+
+func newSummary(result scanner.Result, items []scanner.CleanableItem) Summary {
+	summary := Summary{
+		Items:       len(items),
+		HiddenItems: result.HiddenItems,
+		HiddenBytes: result.HiddenBytes,
+		RecentItems: result.RecentItems,
+		RecentBytes: result.RecentBytes,
+		Incomplete:  result.Incomplete(),
+		Status:      "complete",
+	}
+	switch {
+	case errors.Is(result.Err, context.DeadlineExceeded):
+		summary.Status = "timed_out"
+	case result.Err != nil:
+		summary.Status = "interrupted"
+	}
+	for _, item := range items {
+		summary.SizeBytes += item.Size
+		summary.DiskBytes += item.DiskSize
+	}
+	return summary
+}
+
+func newFilesystems(items []scanner.CleanableItem) []Filesystem {
+	var result []Filesystem
+	for _, u := range cleaner.Filesystems(items) {
+		fs := Filesystem{Mount: u.Mount, Type: u.Type, Items: u.Items, DiskBytes: u.Bytes}
+		if u.Err != nil {
+			fs.Error = u.Err.Error()
+		} else {
+			total, free, after := u.Total, u.Free, u.FreeAfter()
+			fs.TotalBytes, fs.FreeBytes, fs.FreeAfterBytes = &total, &free, &after
+		}
+		result = append(result, fs)
+	}
+	return result
+}
+
+func newErrors(errs []scanner.ScanError) []Error {
+	var result []Error
+	for _, err := range errs {
+		result = append(result, Error{Provider: err.Provider, Path: err.Path, Error: err.Err.Error()})
+	}
+	return result
+}
+
+func newExclusions(excluded []scanner.Exclusion) []Exclusion {
+	var result []Exclusion
+	for _, ex := range excluded {
+		result = append(result, Exclusion{Path: ex.Path, Reason: ex.Reason})
+	}
+	return result
+}
+
+// Write renders doc to w in format, which must be json, yaml, csv or
+// ndjson. For ndjson this writes every item followed by the summary; use a
+// Stream to emit items during the scan instead.
+func Write(w io.Writer, format string, doc Document) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(doc)
+	case "yaml":
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(doc); err != nil {
+			return err
+		}
+		return enc.Close()
+	case "csv":
+		return writeCSV(w, doc.Items)
+	case "ndjson":
+		stream := NewStream(w)
+		for i := range doc.Items {
+			if err := stream.write(Line{Type: "item", Item: &doc.Items[i]}); err != nil {
+				return err
+			}
+		}
+		return stream.Finish(doc)
+	}
+	return fmt.Errorf("unknown output format %q", format)
+}
+
+// csvHeader names the CSV columns; errors is the number of unreadable paths
+var csvHeader = []string{
+	"path", "category", "description", "safety", "rule", "size_bytes", "disk_bytes",
+	"incomplete", "last_used", "age_seconds", "errors", "mount",
+}
+
+func writeCSV(w io.Writer, items []Item) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, item := range items {
+		lastUsed, age := "", ""
+		if item.LastUsed != nil {
+			lastUsed = item.LastUsed.Format(time.RFC3339)
+			age = strconv.FormatInt(*item.AgeSeconds, 10)
+		}
+		record := []string{
+			item.Path, item.Category, item.Description, item.Safety, item.Rule,
+			strconv.FormatInt(item.SizeBytes, 10), strconv.FormatInt(item.DiskBytes, 10),
+			strconv.FormatBool(item.Incomplete), lastUsed, age, strconv.Itoa(len(item.Errors)), item.Mount,
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// Stream writes NDJSON lines as items are found. It is safe for concurrent
+// use, so Item can be called from scan progress callbacks.
+type Stream struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+	now time.Time
+	err error
+}
+
+// NewStream starts an NDJSON stream on w
+func NewStream(w io.Writer) *Stream {
+	return &Stream{enc: json.NewEncoder(w), now: time.Now()}
+}
+
+// Item writes one item line
+func (s *Stream) Item(item scanner.CleanableItem) {
+	converted := NewItem(item, s.now)
+	_ = s.write(Line{Type: "item", Item: &converted})
+}
+
+// Finish writes the summary line from doc and returns the first write
+// error of the stream
+func (s *Stream) Finish(doc Document) error {
+	summary := doc.Summary
+	return s.write(Line{Type: "summary", Summary: &summary, Filesystems: doc.Filesystems, Excluded: doc.Excluded, Errors: doc.Errors})
+}
+
+func (s *Stream) write(line Line) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	line.SchemaVersion = SchemaVersion
+	s.err = s.enc.Encode(line)
+	return s.err
+}
+
+// CleanItem is the outcome of cleaning one item
+type CleanItem struct {
+	Item `yaml:",inline"`
+	// Outcome is "removed", "quarantined", "trashed", "failed" or
+	// "skipped"
+	Outcome    string `json:"outcome" yaml:"outcome"`
+	BytesFreed int64  `json:"bytes_freed" yaml:"bytes_freed"`
+	// TrashPath is where a trashed item was moved to
+	TrashPath string `json:"trash_path,omitempty" yaml:"trash_path,omitempty"`
+	// QuarantineID identifies a quarantined item for agc restore
+	QuarantineID string `json:"quarantine_id,omitempty" yaml:"quarantine_id,omitempty"`
+	Error        string `json:"error,omitempty" yaml:"error,omitempty"`
+	// Reason explains why a skipped item was left alone
+	Reason     string `json:"reason,omitempty" yaml:"reason,omitempty"`
+	DurationMs int64  `json:"duration_ms" yaml:"duration_ms"`
+}
+
+// CleanSummary holds the totals of a clean run
+type CleanSummary struct {
+	Removed     int   `json:"removed" yaml:"removed"`
+	Quarantined int   `json:"quarantined" yaml:"quarantined"`
+	Trashed     int   `json:"trashed" yaml:"trashed"`
+	Failed      int   `json:"failed" yaml:"failed"`
+	Skipped     int   `json:"skipped" yaml:"skipped"`
+	BytesFreed  int64 `json:"bytes_freed" yaml:"bytes_freed"`
+	// BytesQuarantined is freed once the quarantine is purged, and
+	// BytesTrashed once the trash is emptied
+	BytesQuarantined int64 `json:"bytes_quarantined" yaml:"bytes_quarantined"`
+	BytesTrashed     int64 `json:"bytes_trashed" yaml:"bytes_trashed"`
+	DurationMs       int64 `json:"duration_ms" yaml:"duration_ms"`
+	// Status is "success", "partial", "failed" or "nothing_to_do"
+	Status string `json:"status" yaml:"status"`
+}
+
+// CleanDocument is the output of agc clean with --output json or yaml
+type CleanDocument struct {
+	SchemaVersion int `json:"schema_version" yaml:"schema_version"`
+	// Kind is always "clean"
+	Kind        string       `json:"kind" yaml:"kind"`
+	GeneratedAt time.Time    `json:"generated_at" yaml:"generated_at"`
+	Items       []CleanItem  `json:"items" yaml:"items"`
+	Summary     CleanSummary `json:"summary" yaml:"summary"`
+}
+
+// statusNames maps cleaner statuses to their schema names
+var statusNames = map[cleaner.Status]string{
+	cleaner.Success:     "success",
+	cleaner.Partial:     "partial",
+	cleaner.AllFailed:   "failed",
+	cleaner.NothingToDo: "nothing_to_do",
+}
+
+// NewCleanItem converts the outcome of one item
+func NewCleanItem(result cleaner.ItemResult, now time.Time) CleanItem {
+	item := CleanItem{
+		Item:         NewItem(result.Item, now),
+		Outcome:      string(result.Outcome),
+		BytesFreed:   result.BytesFreed,
+		TrashPath:    result.TrashPath,
+		QuarantineID: result.QuarantineID,
+		Reason:       result.Reason,
+		DurationMs:   result.Duration.Milliseconds(),
+	}
+	if result.Err != nil {
+		item.Error = result.Err.Error()
+	}
+	return item
+}
+
+// NewCleanDocument converts a clean report
+func NewCleanDocument(r cleaner.CleanReport) CleanDocument {
+	now := time.Now()
+	doc := CleanDocument{
+		SchemaVersion: SchemaVersion,
+		Kind:          "clean",
+		GeneratedAt:   now.UTC(),
+		Items:         make([]CleanItem, 0, len(r.Items)),
+		Summary: CleanSummary{
+			Removed:          r.Count(cleaner.Removed),
+			Quarantined:      r.Count(cleaner.Quarantined),
+			Trashed:          r.Count(cleaner.Trashed),
+			Failed:           r.Count(cleaner.Failed),
+			Skipped:          r.Count(cleaner.Skipped),
+			BytesFreed:       r.BytesFreed,
+			BytesQuarantined: r.BytesQuarantined,
+			BytesTrashed:     r.BytesTrashed,
+			DurationMs:       r.Duration.Milliseconds(),
+			Status:           statusNames[r.Status()],
+		},
+	}
+	for _, result := range r.Items {
+		doc.Items = append(doc.Items, NewCleanItem(result, now))
+	}
+	return doc
+}
+
+// WriteClean renders a clean report to w in format. ndjson writes one
+// "result" line per item and a final "summary" line.
+func WriteClean(w io.Writer, format string, doc CleanDocument) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(doc)
+	case "yaml":
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(doc); err != nil {
+			return err
+		}
+		return enc.Close()
+	case "csv":
+		return writeCleanCSV(w, doc.Items)
+	case "ndjson":
+		enc := json.NewEncoder(w)
+		for i := range doc.Items {
+			if err := enc.Encode(cleanLine{SchemaVersion: SchemaVersion, Type: "result", Result: &doc.Items[i]}); err != nil {
+				return err
+			}
+		}
+		return enc.Encode(cleanLine{SchemaVersion: SchemaVersion, Type: "summary", Summary: &doc.Summary})
+	}
+	return fmt.Errorf("unknown output format %q", format)
+}
+
+// cleanLine is one line of clean output in ndjson
+type cleanLine struct {
+	SchemaVersion int           `json:"schema_version"`
+	Type          string        `json:"type"`
+	Result        *CleanItem    `json:"result,omitempty"`
+	Summary       *CleanSummary `json:"summary,omitempty"`
+}
+
+func writeCleanCSV(w io.Writer, items []CleanItem) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"path", "category", "description", "safety", "disk_bytes", "outcome", "bytes_freed", "error", "duration_ms", "reason", "trash_path", "quarantine_id"}); err != nil {
+		return err
+	}
+	for _, item := range items {
+		record := []string{
+			item.Path, item.Category, item.Description, item.Safety,
+			strconv.FormatInt(item.DiskBytes, 10), item.Outcome,
+			strconv.FormatInt(item.BytesFreed, 10), item.Error, strconv.FormatInt(item.DurationMs, 10), item.Reason, item.TrashPath, item.QuarantineID,
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,79 @@
+package rules
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/iml1s/antigravity-cleaner/internal/xdg"
+)
+
+// xdgVars resolve to their spec defaults when the environment leaves them unset
+var xdgVars = map[string]func() string{
+	"XDG_CONFIG_HOME": xdg.ConfigHome,
+	"XDG_CACHE_HOME":  xdg.CacheHome,
+	"XDG_DATA_HOME":   xdg.DataHome,
+	"XDG_STATE_HOME":  xdg.StateHome,
+}
+
+// Expand turns a path template into an absolute path. A leading "~" is the
+// home directory, and $VAR or ${VAR} are replaced from the environment, with
+// the XDG base directory variables falling back to their defaults. Unset
+// variables and results that aren't absolute are errors, so a rule can never
+// resolve relative to the working directory.
+func Expand(tmpl string) (string, error) {
+	var missing []string
+	expanded := os.Expand(tmpl, func(name string) string {
+		value := lookupVar(name)
+		if value == "" {
+			missing = append(missing, name)
+		}
+		return value
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("%s: %s is not set", tmpl, strings.Join(missing, ", "))
+	}
+
+	if expanded == "~" || strings.HasPrefix(expanded, "~/") {
+		home := lookupVar("HOME")
+		if home == "" {
+			return "", fmt.Errorf("%s: home directory is not set", tmpl)
+		}
+		expanded = home + expanded[1:]
+	}
+
+	path := filepath.Clean(filepath.FromSlash(expanded))
+	if !filepath.IsAbs(path) {
+		return "", fmt.Errorf("%s: expands to relative path %q", tmpl, path)
+	}
+	return path, nil
+}
+
+func lookupVar(name string) string {
+	if resolve, ok := xdgVars[name]; ok {
+		return resolve()
+	}
+	if name == "HOME" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		return home
+	}
+	return os.Getenv(name)
+}
+
+// checkTemplate reports syntax problems that don't depend on the environment
+func checkTemplate(tmpl string) error {
+	if strings.TrimSpace(tmpl) == "" {
+		return fmt.Errorf("empty path template")
+	}
+	if strings.Count(tmpl, "${") != strings.Count(tmpl, "}") {
+		return fmt.Errorf("%s: unbalanced ${...}", tmpl)
+	}
+	if !strings.HasPrefix(tmpl, "~") && !strings.HasPrefix(tmpl, "$") && !filepath.IsAbs(filepath.FromSlash(tmpl)) && !strings.HasPrefix(tmpl, "/") {
+		return fmt.Errorf("%s: must start with ~, a variable, or an absolute path", tmpl)
+	}
+	return nil
+}
@@ -0,0 +1,322 @@
+// Package rules loads the declarative descriptions of cleanable paths.
+//
+// Built-in rules are embedded from builtin/*.yaml. Additional rule files are
+// read from $XDG_CONFIG_HOME/agc/rules.d, and a user rule replaces a built-in
+// rule with the same ID.
+package rules
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+	"github.com/iml1s/antigravity-cleaner/internal/xdg"
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed builtin/*.yaml
+var builtinFS embed.FS
+
+// Rule describes one cleanable path, with a template per platform
+type Rule struct {
+	ID          string `yaml:"id"`
+	Category    string `yaml:"category"`
+	Description string `yaml:"description"`
+	Safety      string `yaml:"safety"`
+	// MinSize hides the item unless it is larger than this, e.g. "100MiB"
+	MinSize string `yaml:"min_size,omitempty"`
+	// Paths maps a GOOS value, or "all", to path templates
+	Paths map[string][]string `yaml:"paths"`
+	// Processes names the programs that own the data, such as code or
+	// GradleDaemon; the item isn't cleaned while one of them runs
+	Processes []string `yaml:"processes,omitempty"`
+
+	// Source is the file the rule was loaded from
+	Source string `yaml:"-"`
+}
+
+// File is the top-level document of a rule file
+type File struct {
+	Rules []Rule `yaml:"rules"`
+}
+
+// SafetyLevels lists the accepted values of Rule.Safety
+var SafetyLevels = []string{"safe", "caution", "warning"}
+
+// SafetyRank orders safety levels from least to most risky, or returns -1
+// for an unknown level
+func SafetyRank(level string) int {
+	for i, l := range SafetyLevels {
+		if l == level {
+			return i
+		}
+	}
+	return -1
+}
+
+var platforms = []string{"all", "darwin", "linux", "windows"}
+
+// MinBytes returns the parsed minimum size, or 0 when none is set
+func (r Rule) MinBytes() int64 {
+	if r.MinSize == "" {
+		return 0
+	}
+	n, err := humanize.ParseBytes(r.MinSize)
+	if err != nil {
+		return 0
+	}
+	return int64(n)
+}
+
+// Templates returns the path templates that apply to goos
+func (r Rule) Templates(goos string) []string {
+	return append(append([]string(nil), r.Paths["all"]...), r.Paths[goos]...)
+}
+
+// ExpandPaths expands the rule's templates for goos. Templates that can't be
+// expanded, for example because a variable is unset, are reported in errs
+// and left out of paths.
+func (r Rule) ExpandPaths(goos string) (paths []string, errs []error) {
+	for _, tmpl := range r.Templates(goos) {
+		path, err := Expand(tmpl)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("rule %s: %w", r.ID, err))
+			continue
+		}
+		paths = append(paths, path)
+	}
+	return paths, errs
+}
+
+// Validate checks the rule for missing or malformed fields
+func (r Rule) Validate() error {
+	var errs []error
+	if r.ID == "" {
+		errs = append(errs, errors.New("missing id"))
+	}
+	if r.Category == "" {
+		errs = append(errs, errors.New("missing category"))
+	}
+	if r.Description == "" {
+		errs = append(errs, errors.New("missing description"))
+	}
+	if !contains(SafetyLevels, r.Safety) {
+		errs = append(errs, fmt.Errorf("safety must be one of %s, got %q", strings.Join(SafetyLevels, ", "), r.Safety))
+	}
+	if r.MinSize != "" {
+		if _, err := humanize.ParseBytes(r.MinSize); err != nil {
+			errs = append(errs, fmt.Errorf("invalid min_size %q", r.MinSize))
+		}
+	}
+	if len(r.Paths) == 0 {
+		errs = append(errs, errors.New("no paths"))
+	}
+	for _, name := range r.Processes {
+		if strings.TrimSpace(name) == "" {
+			errs = append(errs, errors.New("empty process name"))
+		}
+	}
+	keys := make([]string, 0, len(r.Paths))
+	for goos := range r.Paths {
+		keys = append(keys, goos)
+	}
+	sort.Strings(keys)
+	for _, goos := range keys {
+		if !contains(platforms, goos) {
+			errs = append(errs, fmt.Errorf("unknown platform %q (use one of %s)", goos, strings.Join(platforms, ", ")))
+		}
+		for _, tmpl := range r.Paths[goos] {
+			if err := checkTemplate(tmpl); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+
+	id := r.ID
+	if id == "" {
+		id = "<unnamed>"
+	}
+	msgs := make([]string, len(errs))
+	for i, err := range errs {
+		msgs[i] = err.Error()
+	}
+	return fmt.Errorf("rule %s: %s", id, strings.Join(msgs, "; "))
+}
+
+// Builtin returns the rules embedded in the binary
+func Builtin() ([]Rule, error) {
+	entries, err := builtinFS.ReadDir("builtin")
+	if err != nil {
+		return nil, err
+	}
+
+	var result []Rule
+	for _, entry := range entries {
+		data, err := builtinFS.ReadFile("builtin/" + entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		rules, err := Parse(data, "builtin:"+entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, rules...)
+	}
+	return result, nil
+}
+
+// UserDir returns the directory extra rule files are read from
+func UserDir() string {
+	base := xdg.ConfigHome()
+	if base == "" {
+		return ""
+	}
+	return filepath.Join(base, "agc", "rules.d")
+}
+
+// LoadFile reads and validates a single rule file
+func LoadFile(path string) ([]Rule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data, path)
+}
+
+// Files returns the .yaml/.yml files in dir in name order. A missing
+// directory yields no files.
+func Files(dir string) ([]string, error) {
+	if dir == "" {
+		return nil, nil
+	}
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if entry.IsDir() || (ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+		files = append(files, filepath.Join(dir, entry.Name()))
+	}
+	return files, nil
+}
+
+// LoadDir reads every rule file in dir. Files that fail to load are skipped
+// and their errors returned alongside the rules that did load.
+func LoadDir(dir string) ([]Rule, error) {
+	files, err := Files(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []Rule
+	var errs []error
+	for _, path := range files {
+		rules, err := LoadFile(path)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		result = append(result, rules...)
+	}
+	return result, errors.Join(errs...)
+}
+
+// Load returns the built-in rules merged with the rules in UserDir. Broken
+// user files are reported in the error but don't prevent the rest loading.
+func Load() ([]Rule, error) {
+	builtin, err := Builtin()
+	if err != nil {
+		return nil, fmt.Errorf("built-in rules: %w", err)
+	}
+	user, err := LoadDir(UserDir())
+	return Merge(builtin, user), err
+}
+
+// Merge returns base with extra appended; a rule in extra replaces the rule
+// in base with the same ID, keeping its position
+func Merge(base, extra []Rule) []Rule {
+	result := append([]Rule(nil), base...)
+	index := make(map[string]int, len(result))
+	for i, r := range result {
+		index[r.ID] = i
+	}
+	for _, r := range extra {
+		if i, ok := index[r.ID]; ok {
+			result[i] = r
+			continue
+		}
+		index[r.ID] = len(result)
+		result = append(result, r)
+	}
+	return result
+}
+
+// Categories returns the distinct categories in rules, sorted
+func Categories(rules []Rule) []string {
+	seen := make(map[string]bool)
+	var result []string
+	for _, r := range rules {
+		if !seen[r.Category] {
+			seen[r.Category] = true
+			result = append(result, r.Category)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// Parse decodes and validates a rule document. source names the document in
+// error messages and is recorded on each rule.
+func Parse(data []byte, source string) ([]Rule, error) {
+	var file File
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&file); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("%s: %w", source, err)
+	}
+
+	var errs []error
+	seen := make(map[string]bool)
+	for i := range file.Rules {
+		r := &file.Rules[i]
+		r.Source = source
+		if err := r.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+		if r.ID != "" && seen[r.ID] {
+			errs = append(errs, fmt.Errorf("rule %s: duplicate id", r.ID))
+		}
+		seen[r.ID] = true
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("%s: %w", source, errors.Join(errs...))
+	}
+	return file.Rules, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
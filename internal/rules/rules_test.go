@@ -0,0 +1,178 @@
+package rules
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestBuiltin(t *testing.T) {
+	rules, err := Builtin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) == 0 {
+		t.Fatal("no built-in rules")
+	}
+	seen := make(map[string]bool)
+	for _, r := range rules {
+		if seen[r.ID] {
+			t.Errorf("rule %s defined twice", r.ID)
+		}
+		seen[r.ID] = true
+		if !strings.HasPrefix(r.Source, "builtin:") {
+			t.Errorf("rule %s: source %q", r.ID, r.Source)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	doc := `
+rules:
+  - id: gradle-caches
+    category: Android
+    description: Gradle caches
+    safety: caution
+    min_size: 100MiB
+    paths:
+      all: [~/.gradle/caches]
+      windows: ['${LOCALAPPDATA}/Gradle']
+    processes: [GradleDaemon]
+`
+	rules, err := Parse([]byte(doc), "test.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 1 {
+		t.Fatalf("got %d rules, want 1", len(rules))
+	}
+	r := rules[0]
+	if r.Source != "test.yaml" || r.MinBytes() != 100<<20 || len(r.Processes) != 1 {
+		t.Errorf("parsed %+v", r)
+	}
+	if got := r.Templates("windows"); len(got) != 2 || got[1] != "${LOCALAPPDATA}/Gradle" {
+		t.Errorf("windows templates %q", got)
+	}
+	if got := r.Templates("linux"); len(got) != 1 {
+		t.Errorf("linux templates %q", got)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	rule := func(extra string) string {
+		return "rules:\n  - id: r\n    category: C\n    description: D\n" + extra
+	}
+	tests := []struct {
+		name string
+		doc  string
+		want string
+	}{
+		{"unknown field", rule("    safety: safe\n    paths: {all: [~/x]}\n    colour: red\n"), "colour"},
+		{"bad safety", rule("    safety: risky\n    paths: {all: [~/x]}\n"), "safety must be one of"},
+		{"no paths", rule("    safety: safe\n"), "no paths"},
+		{"bad platform", rule("    safety: safe\n    paths: {plan9: [~/x]}\n"), `unknown platform "plan9"`},
+		{"relative path", rule("    safety: safe\n    paths: {all: [x/y]}\n"), "must start with ~"},
+		{"unbalanced variable", rule("    safety: safe\n    paths: {all: ['${HOME/x']}\n"), "unbalanced"},
+		{"bad min_size", rule("    safety: safe\n    min_size: lots\n    paths: {all: [~/x]}\n"), "invalid min_size"},
+		{"empty process", rule("    safety: safe\n    paths: {all: [~/x]}\n    processes: ['']\n"), "empty process name"},
+		{"duplicate id", rule("    safety: safe\n    paths: {all: [~/x]}\n") + strings.TrimPrefix(rule("    safety: safe\n    paths: {all: [~/y]}\n"), "rules:\n"), "duplicate id"},
+	}
+	for _, tt := range tests {
+		_, err := Parse([]byte(tt.doc), "test.yaml")
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: got %v, want an error containing %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+	base := []Rule{{ID: "a", Category: "A"}, {ID: "b", Category: "B"}}
+	extra := []Rule{{ID: "c", Category: "C"}, {ID: "a", Category: "Z"}}
+	merged := Merge(base, extra)
+
+	var got []string
+	for _, r := range merged {
+		got = append(got, r.ID+"="+r.Category)
+	}
+	if strings.Join(got, " ") != "a=Z b=B c=C" {
+		t.Errorf("merged %q", got)
+	}
+	if base[0].Category != "A" {
+		t.Error("Merge modified base")
+	}
+	if cats := Categories(merged); strings.Join(cats, ",") != "B,C,Z" {
+		t.Errorf("categories %q", cats)
+	}
+}
+
+func TestLoadDir(t *testing.T) {
+	dir := t.TempDir()
+	good := "rules:\n  - id: good\n    category: C\n    description: D\n    safety: safe\n    paths: {all: [~/x]}\n"
+	files := map[string]string{
+		"a.yaml":    good,
+		"b.yml":     "rules:\n  - id: bad\n",
+		"notes.txt": "not a rule file",
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rules, err := LoadDir(dir)
+	if err == nil || !strings.Contains(err.Error(), "b.yml") {
+		t.Errorf("got error %v, want one naming b.yml", err)
+	}
+	if len(rules) != 1 || rules[0].ID != "good" {
+		t.Errorf("loaded %+v", rules)
+	}
+
+	if rules, err := LoadDir(filepath.Join(dir, "missing")); err != nil || rules != nil {
+		t.Errorf("missing dir: got %v, %v", rules, err)
+	}
+}
+
+func TestExpand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("templates below use Unix paths")
+	}
+	t.Setenv("HOME", "/home/dev")
+	t.Setenv("XDG_CACHE_HOME", "")
+	t.Setenv("AGC_TEST_DIR", "/srv/data")
+	t.Setenv("AGC_TEST_EMPTY", "")
+
+	tests := []struct {
+		tmpl string
+		want string
+		err  string
+	}{
+		{tmpl: "~", want: "/home/dev"},
+		{tmpl: "~/.gradle/caches", want: "/home/dev/.gradle/caches"},
+		{tmpl: "${XDG_CACHE_HOME}/go-build", want: "/home/dev/.cache/go-build"},
+		{tmpl: "$AGC_TEST_DIR/cache/../tmp", want: "/srv/data/tmp"},
+		{tmpl: "${AGC_TEST_EMPTY}/x", err: "AGC_TEST_EMPTY is not set"},
+		{tmpl: "${AGC_TEST_DIR}x/..", want: "/srv"},
+		{tmpl: "relative/${AGC_TEST_DIR}", err: "relative path"},
+		{tmpl: "~user/x", err: "relative path"},
+	}
+	for _, tt := range tests {
+		got, err := Expand(tt.tmpl)
+		switch {
+		case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+			t.Errorf("Expand(%q) = %q, %v; want an error containing %q", tt.tmpl, got, err, tt.err)
+		case tt.err == "" && (err != nil || got != tt.want):
+			t.Errorf("Expand(%q) = %q, %v; want %q", tt.tmpl, got, err, tt.want)
+		}
+	}
+}
+
+func TestSafetyRank(t *testing.T) {
+	if !(SafetyRank("safe") < SafetyRank("caution") && SafetyRank("caution") < SafetyRank("warning")) {
+		t.Error("safety levels are out of order")
+	}
+	if SafetyRank("") != -1 || SafetyRank("Safe") != -1 {
+		t.Error("unknown levels should rank -1")
+	}
+}
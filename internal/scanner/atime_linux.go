@@ -0,0 +1,29 @@
+package scanner
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// stNoatime is ST_NOATIME from <sys/statvfs.h>
+const stNoatime = 0x400
+
+// fileAtime returns a file's last access time, or zero if unknown
+func fileAtime(info os.FileInfo) time.Time {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return time.Time{}
+	}
+	return time.Unix(int64(st.Atim.Sec), int64(st.Atim.Nsec))
+}
+
+// atimeReliable reports whether the filesystem holding path records access
+// times; it doesn't when mounted noatime
+func atimeReliable(path string) bool {
+	var fs syscall.Statfs_t
+	if err := syscall.Statfs(path, &fs); err != nil {
+		return false
+	}
+	return fs.Flags&stNoatime == 0
+}
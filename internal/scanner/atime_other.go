@@ -0,0 +1,19 @@
+//go:build !linux && !darwin
+
+package scanner
+
+import (
+	"os"
+	"time"
+)
+
+// fileAtime returns zero: access times aren't read on this platform, since
+// whether the filesystem maintains them can't be checked cheaply
+func fileAtime(info os.FileInfo) time.Time {
+	return time.Time{}
+}
+
+// atimeReliable always reports false on this platform
+func atimeReliable(path string) bool {
+	return false
+}
@@ -0,0 +1,136 @@
+package scanner
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iml1s/antigravity-cleaner/internal/sizecache"
+)
+
+// cachedTree builds a small project tree whose directories were last
+// modified an hour ago, so their listings are old enough to cache
+func cachedTree(t *testing.T) string {
+	t.Helper()
+	root := filepath.Join(t.TempDir(), "build")
+	for rel, size := range map[string]int{
+		"a.bin":             10_000,
+		"deep/b.bin":        20_000,
+		"deep/er/c.bin":     30_000,
+		"deep/er/est/d.bin": 40_000,
+	} {
+		path := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(strings.Repeat("x", size)), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ageDirs(t, root)
+	return root
+}
+
+// ageDirs sets the mtime of every directory under root an hour back
+func ageDirs(t *testing.T, root string) {
+	t.Helper()
+	old := time.Now().Add(-time.Hour)
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return err
+		}
+		return os.Chtimes(path, old, old)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// measureWith sizes root as a single item, using cache if given
+func measureWith(t *testing.T, root string, cache *sizecache.Cache) CleanableItem {
+	t.Helper()
+	zero := int64(0)
+	s := NewSession(Options{Thresholds: Thresholds{Global: &zero}, Cache: cache})
+	items := s.measure(context.Background(), []candidate{{item: CleanableItem{Path: root}}})
+	if len(items) != 1 {
+		t.Fatalf("got %d items, want 1", len(items))
+	}
+	return items[0]
+}
+
+func TestCachedRescanAfterDelete(t *testing.T) {
+	root := cachedTree(t)
+	cache := sizecache.Open(filepath.Join(t.TempDir(), "dirsizes.gob"))
+
+	first := measureWith(t, root, cache)
+	if first.Size != 100_000 {
+		t.Fatalf("first scan: apparent size %d, want 100000", first.Size)
+	}
+	if cache.Len() != 4 {
+		t.Fatalf("cached %d directories, want 4", cache.Len())
+	}
+
+	// Deleting a file changes only its own directory's mtime; the cached
+	// listings above it still match and are reused
+	if err := os.Remove(filepath.Join(root, "deep", "er", "est", "d.bin")); err != nil {
+		t.Fatal(err)
+	}
+	second := measureWith(t, root, cache)
+	if second.Size != 60_000 {
+		t.Errorf("rescan with the cache: apparent size %d, want 60000", second.Size)
+	}
+	if uncached := measureWith(t, root, nil); second.DiskSize != uncached.DiskSize {
+		t.Errorf("rescan with the cache: disk size %d, a fresh walk says %d", second.DiskSize, uncached.DiskSize)
+	}
+
+	// So does deleting a whole directory
+	if err := os.RemoveAll(filepath.Join(root, "deep", "er")); err != nil {
+		t.Fatal(err)
+	}
+	if third := measureWith(t, root, cache); third.Size != 30_000 {
+		t.Errorf("rescan after removing a directory: apparent size %d, want 30000", third.Size)
+	}
+}
+
+func TestRacyListingsNotCached(t *testing.T) {
+	root := cachedTree(t)
+	// Touch one directory as if it changed just as the scan began
+	now := time.Now()
+	if err := os.Chtimes(filepath.Join(root, "deep"), now, now); err != nil {
+		t.Fatal(err)
+	}
+	cache := sizecache.Open("")
+	measureWith(t, root, cache)
+	if cache.Len() != 3 {
+		t.Errorf("cached %d directories, want 3 without the racy one", cache.Len())
+	}
+	info, err := os.Lstat(filepath.Join(root, "deep"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cache.Lookup(filepath.Join(root, "deep"), signature(info)); ok {
+		t.Error("the racily modified directory was cached")
+	}
+}
+
+func TestCacheReused(t *testing.T) {
+	root := cachedTree(t)
+	cache := sizecache.Open("")
+	measureWith(t, root, cache)
+
+	// Growing a file in place leaves its directory's mtime alone, so a
+	// rescan reusing the listing still reports the old size
+	if err := os.WriteFile(filepath.Join(root, "deep", "b.bin"), []byte(strings.Repeat("x", 25_000)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got := measureWith(t, root, cache).Size; got != 100_000 {
+		t.Errorf("rescan with the cache: apparent size %d, want the cached 100000", got)
+	}
+	if got := measureWith(t, root, nil).Size; got != 105_000 {
+		t.Errorf("rescan without the cache: apparent size %d, want 105000", got)
+	}
+}
@@ -0,0 +1,94 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"sync"
+)
+
+// ScanError records a path that couldn't be read, leaving a size or an item
+// list incomplete
+type ScanError struct {
+	// Provider is the ID of the provider that hit the error
+	Provider string
+	Path     string
+	Err      error
+}
+
+func (e ScanError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Path, e.Err)
+}
+
+func (e ScanError) Unwrap() error {
+	return e.Err
+}
+
+// newScanError strips the path and operation that os wraps around errors,
+// since Path already says where it happened
+func newScanError(ctx context.Context, path string, err error) ScanError {
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		err = pathErr.Err
+	}
+	provider, _ := ctx.Value(providerKey{}).(string)
+	return ScanError{Provider: provider, Path: path, Err: err}
+}
+
+// errorList collects errors from concurrent walkers
+type errorList struct {
+	mu   sync.Mutex
+	errs []ScanError
+}
+
+func (l *errorList) add(err ScanError) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.errs = append(l.errs, err)
+}
+
+// sorted returns the errors ordered by path, so output doesn't depend on
+// which walker hit its error first
+func (l *errorList) sorted() []ScanError {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	result := append([]ScanError(nil), l.errs...)
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Path < result[j].Path
+	})
+	return result
+}
+
+// report records an error that isn't tied to a single item, such as an
+// unreadable directory while looking for projects
+func (s *Session) report(ctx context.Context, path string, err error) {
+	s.errs.add(newScanError(ctx, path, err))
+}
+
+// providerErrors returns the errors reported by the given providers, in
+// provider order
+func (s *Session) providerErrors(providers []Provider) []ScanError {
+	all := s.errs.sorted()
+	var result []ScanError
+	for _, p := range providers {
+		for _, err := range all {
+			if err.Provider == p.ID() {
+				result = append(result, err)
+			}
+		}
+	}
+	return result
+}
+
+// exists checks if a path exists, reporting errors other than the path
+// simply not being there
+func (s *Session) exists(ctx context.Context, path string) bool {
+	_, err := os.Stat(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		s.report(ctx, path, err)
+	}
+	return err == nil
+}
@@ -0,0 +1,201 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/iml1s/antigravity-cleaner/internal/ignore"
+)
+
+// Exclusion records a path left out because of an exclude pattern
+type Exclusion struct {
+	Path string
+	// Reason names the pattern and where it came from
+	Reason string
+}
+
+// exclusions is the compiled form of Options.Exclude
+type exclusions struct {
+	// paths are the literal absolute entries; an item containing one is
+	// dropped too, since cleaning it would remove the excluded path
+	paths []string
+	// patterns holds every entry, matched against the absolute path
+	patterns *ignore.Matcher
+	// entries maps the compiled patterns back to the configured entries
+	entries map[string]string
+
+	mu       sync.Mutex
+	excluded []Exclusion
+}
+
+func newExclusions(entries []string) *exclusions {
+	e := &exclusions{entries: make(map[string]string)}
+	var lines []string
+	for _, entry := range entries {
+		configured := entry
+		if filepath.IsAbs(entry) {
+			if !strings.ContainsAny(entry, "*?[") {
+				e.paths = append(e.paths, filepath.Clean(entry))
+			}
+			// Anchor absolute entries at the root
+			entry = "/" + strings.TrimPrefix(filepath.ToSlash(entry), "/")
+		} else {
+			entry = anyDepth(entry)
+		}
+		e.entries[entry] = configured
+		lines = append(lines, entry)
+	}
+	e.patterns = ignore.New(lines)
+	return e
+}
+
+// anyDepth makes a relative pattern match at any depth. The patterns are
+// matched against absolute paths, where gitignore would anchor one with a
+// slash in it, such as signed-*/build/, at the filesystem root.
+func anyDepth(entry string) string {
+	negate := strings.HasPrefix(entry, "!")
+	entry = strings.TrimPrefix(entry, "!")
+	if entry != "" && !strings.HasPrefix(entry, "#") && !strings.HasPrefix(entry, "**/") {
+		entry = "**/" + strings.TrimPrefix(entry, "/")
+	}
+	if negate {
+		entry = "!" + entry
+	}
+	return entry
+}
+
+// record notes that path was excluded
+func (e *exclusions) record(path, reason string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.excluded = append(e.excluded, Exclusion{Path: path, Reason: reason})
+}
+
+func (e *exclusions) sorted() []Exclusion {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	result := append([]Exclusion(nil), e.excluded...)
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Path < result[j].Path
+	})
+	return result
+}
+
+// excludedBy returns why the configuration excludes path, or "" if it
+// doesn't
+func (s *Session) excludedBy(path string, isDir bool) string {
+	if raw, ok := s.exclude.patterns.Match(filepath.ToSlash(path), isDir); ok {
+		return "config: " + s.exclude.entries[raw]
+	}
+	return ""
+}
+
+// touchesExcluded returns why cleaning path would remove an excluded path,
+// either because path is excluded or because it contains one, or ""
+func (s *Session) touchesExcluded(path string) string {
+	if reason := s.excludedBy(path, true); reason != "" {
+		return reason
+	}
+	for _, ex := range s.exclude.paths {
+		if within(ex, path) && exists(ex) {
+			return "contains excluded " + ex
+		}
+	}
+	return ""
+}
+
+// ignoreFrame holds the .agcignore patterns of one directory
+type ignoreFrame struct {
+	dir string
+	m   *ignore.Matcher
+}
+
+// walkProjects walks root like filepath.Walk but only calls visit for
+// directories, and skips those excluded by the configuration or by an
+// .agcignore file in them or any directory above them within root. Errors
+// are reported to the session, except a missing root when reportMissing
+// is false.
+func (s *Session) walkProjects(ctx context.Context, root string, reportMissing bool, visit func(path string, info fs.FileInfo) error) {
+	// Exclude patterns are expanded to absolute paths, so the walk has to
+	// produce absolute paths for them to match
+	if abs, err := filepath.Abs(root); err == nil {
+		root = abs
+	}
+	var stack []ignoreFrame
+	_ = filepath.Walk(root, func(path string, info fs.FileInfo, err error) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			if reportMissing || path != root || !errors.Is(err, fs.ErrNotExist) {
+				s.report(ctx, path, err)
+			}
+			return nil
+		}
+		if !info.IsDir() {
+			return nil
+		}
+
+		// Walk visits directories depth first, so frames for directories
+		// that aren't ancestors of path are finished
+		for len(stack) > 0 && !within(path, stack[len(stack)-1].dir) {
+			stack = stack[:len(stack)-1]
+		}
+		if reason := s.ignoredBy(path, stack); reason != "" {
+			s.exclude.record(path, reason)
+			return filepath.SkipDir
+		}
+
+		ignoreFile := filepath.Join(path, ignore.FileName)
+		m, err := ignore.ReadFile(ignoreFile)
+		switch {
+		case err == nil && !m.Empty():
+			stack = append(stack, ignoreFrame{dir: path, m: m})
+		case err != nil && !errors.Is(err, fs.ErrNotExist):
+			s.report(ctx, ignoreFile, err)
+		}
+		return visit(path, info)
+	})
+}
+
+// ignoredBy returns why path is excluded by the configuration or the
+// .agcignore files in stack, or ""
+func (s *Session) ignoredBy(path string, stack []ignoreFrame) string {
+	if reason := s.excludedBy(path, true); reason != "" {
+		return reason
+	}
+	for _, frame := range stack {
+		rel, err := filepath.Rel(frame.dir, path)
+		if err != nil {
+			continue
+		}
+		if raw, ok := frame.m.Match(filepath.ToSlash(rel), true); ok {
+			return filepath.Join(frame.dir, ignore.FileName) + ": " + raw
+		}
+	}
+	return ""
+}
+
+// exists reports whether anything is at path, without reporting errors
+func exists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
+
+// within reports whether path is dir or inside it
+func within(path, dir string) bool {
+	path, dir = filepath.Clean(path), filepath.Clean(dir)
+	if path == dir {
+		return true
+	}
+	if !strings.HasSuffix(dir, string(filepath.Separator)) {
+		dir += string(filepath.Separator)
+	}
+	return strings.HasPrefix(path, dir)
+}
@@ -0,0 +1,83 @@
+package scanner
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestExcludePatterns(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{
+		"proj/signed-x/build",
+		"proj/signed-x/lib",
+		"proj/other/build",
+		"proj/deep/er/signed-y/build",
+		"proj/keep/build",
+		"proj/vendor/cache",
+	} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := NewSession(Options{Exclude: []string{
+		"signed-*/build/",
+		"vendor",
+		filepath.Join(root, "proj", "keep"),
+	}})
+	var visited []string
+	s.walkProjects(context.Background(), root, true, func(path string, info fs.FileInfo) error {
+		rel, _ := filepath.Rel(root, path)
+		visited = append(visited, filepath.ToSlash(rel))
+		return nil
+	})
+	want := []string{".", "proj", "proj/deep", "proj/deep/er", "proj/deep/er/signed-y", "proj/other", "proj/other/build", "proj/signed-x", "proj/signed-x/lib"}
+	sort.Strings(visited)
+	if strings.Join(visited, " ") != strings.Join(want, " ") {
+		t.Errorf("visited %q\nwant    %q", visited, want)
+	}
+
+	reasons := make(map[string]string)
+	for _, ex := range s.exclude.sorted() {
+		reasons[ex.Path] = ex.Reason
+	}
+	for rel, reason := range map[string]string{
+		"proj/signed-x/build":         "config: signed-*/build/",
+		"proj/deep/er/signed-y/build": "config: signed-*/build/",
+		"proj/vendor":                 "config: vendor",
+		"proj/keep":                   "config: " + filepath.Join(root, "proj", "keep"),
+	} {
+		if got := reasons[filepath.Join(root, rel)]; got != reason {
+			t.Errorf("%s excluded by %q, want %q", rel, got, reason)
+		}
+	}
+
+	// Items are checked against the same patterns
+	if reason := s.touchesExcluded(filepath.Join(root, "proj", "signed-x", "build")); reason == "" {
+		t.Error("item signed-x/build isn't excluded")
+	}
+	if reason := s.touchesExcluded(filepath.Join(root, "proj")); !strings.Contains(reason, "contains excluded") {
+		t.Errorf("item holding an excluded path: reason %q", reason)
+	}
+}
+
+func TestAnyDepth(t *testing.T) {
+	tests := map[string]string{
+		"build":            "**/build",
+		"signed-*/build/":  "**/signed-*/build/",
+		"/out":             "**/out",
+		"!signed-x/build/": "!**/signed-x/build/",
+		"**/tmp":           "**/tmp",
+		"# comment":        "# comment",
+	}
+	for in, want := range tests {
+		if got := anyDepth(in); got != want {
+			t.Errorf("anyDepth(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package scanner
+
+import "context"
+
+// EventKind identifies what a progress Event reports
+type EventKind int
+
+const (
+	// DirEntered is sent when a directory walk reaches Path
+	DirEntered EventKind = iota
+	// BytesCounted is sent as file sizes are added up; Bytes is the running
+	// total for the whole session
+	BytesCounted
+	// ItemFound is sent for every item a provider will return
+	ItemFound
+	// ProviderDone is sent when a provider's scan returns; Items is the
+	// number of items it found
+	ProviderDone
+)
+
+// Event is a progress notification from a running scan
+type Event struct {
+	Kind EventKind
+	// Provider is the ID of the provider the event belongs to
+	Provider string
+	Path     string
+	Bytes    int64
+	Item     *CleanableItem
+	Items    int
+}
+
+type providerKey struct{}
+
+// withProvider tags ctx so events emitted below it carry p's ID
+func withProvider(ctx context.Context, p Provider) context.Context {
+	return context.WithValue(ctx, providerKey{}, p.ID())
+}
+
+// emit delivers ev to the session's progress callback, if any
+func (s *Session) emit(ctx context.Context, ev Event) {
+	if s.progress == nil {
+		return
+	}
+	if ev.Provider == "" {
+		ev.Provider, _ = ctx.Value(providerKey{}).(string)
+	}
+	s.progress(ev)
+}
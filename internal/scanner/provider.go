@@ -0,0 +1,188 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// Provider is a source of cleanable items for a single tool or category
+type Provider interface {
+	// ID is the short lowercase name used for subcommands and --only/--skip
+	ID() string
+	// Name is the category label attached to every item the provider returns
+	Name() string
+	// Description is a one-line summary shown in command help
+	Description() string
+	// Platforms lists the GOOS values the provider supports; empty means all
+	Platforms() []string
+	// Scan returns the cleanable items found on this machine
+	Scan(ctx context.Context, s *Session) []CleanableItem
+}
+
+// Cleaner is implemented by providers whose items need custom removal logic
+// instead of deleting Path from disk. Clean returns one error per item, nil
+// for items removed successfully.
+type Cleaner interface {
+	Clean(items []CleanableItem) []error
+}
+
+// PathScanner is implemented by providers that walk a user-supplied base path
+type PathScanner interface {
+	ScanPath(ctx context.Context, s *Session, basePath string) []CleanableItem
+}
+
+var registry []Provider
+
+// Register adds a provider to the registry. Registering two providers with
+// the same ID or name is a programming error and panics.
+func Register(p Provider) {
+	for _, existing := range registry {
+		if strings.EqualFold(existing.ID(), p.ID()) || strings.EqualFold(existing.Name(), p.Name()) {
+			panic(fmt.Sprintf("scanner: provider %q registered twice", p.ID()))
+		}
+	}
+	registry = append(registry, p)
+}
+
+// Providers returns every registered provider in registration order
+func Providers() []Provider {
+	return append([]Provider(nil), registry...)
+}
+
+// Available returns the registered providers supported on the current OS
+func Available() []Provider {
+	var result []Provider
+	for _, p := range registry {
+		if Supports(p, runtime.GOOS) {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+// Supports reports whether p can run on the given GOOS
+func Supports(p Provider, goos string) bool {
+	platforms := p.Platforms()
+	if len(platforms) == 0 {
+		return true
+	}
+	for _, platform := range platforms {
+		if platform == goos {
+			return true
+		}
+	}
+	return false
+}
+
+// Lookup finds a provider by ID or category name, ignoring case
+func Lookup(name string) Provider {
+	for _, p := range registry {
+		if strings.EqualFold(p.ID(), name) || strings.EqualFold(p.Name(), name) {
+			return p
+		}
+	}
+	return nil
+}
+
+// Select returns the available providers filtered by the --only and --skip
+// lists. Unknown names are reported as an error.
+func Select(only, skip []string) ([]Provider, error) {
+	for _, name := range append(append([]string(nil), only...), skip...) {
+		if Lookup(name) == nil {
+			return nil, fmt.Errorf("unknown category %q (available: %s)", name, strings.Join(providerIDs(), ", "))
+		}
+	}
+
+	var result []Provider
+	for _, p := range Available() {
+		if len(only) > 0 && !matchesAny(p, only) {
+			continue
+		}
+		if matchesAny(p, skip) {
+			continue
+		}
+		result = append(result, p)
+	}
+	return result, nil
+}
+
+// ScanProviders runs the providers concurrently and concatenates their
+// items in provider order, so the output doesn't depend on scheduling
+func ScanProviders(ctx context.Context, s *Session, providers []Provider) Result {
+	perProvider := make([][]CleanableItem, len(providers))
+	g := s.group()
+	for i, p := range providers {
+		i, p := i, p
+		g.Go(func() {
+			if ctx.Err() == nil {
+				perProvider[i] = scanOne(ctx, s, p, "")
+			}
+		})
+	}
+	g.Wait()
+
+	var result Result
+	for _, items := range perProvider {
+		result.Items = append(result.Items, items...)
+	}
+	result.finish(ctx, s, providers)
+	return result
+}
+
+// ScanProvider scans a single provider. A non-empty basePath is passed to
+// providers that implement PathScanner and ignored by the rest.
+func ScanProvider(ctx context.Context, s *Session, p Provider, basePath string) Result {
+	result := Result{Items: scanOne(ctx, s, p, basePath)}
+	result.finish(ctx, s, []Provider{p})
+	return result
+}
+
+// scanOne runs a single provider and reports when it's done
+func scanOne(ctx context.Context, s *Session, p Provider, basePath string) []CleanableItem {
+	ctx = withProvider(ctx, p)
+	var items []CleanableItem
+	if ps, ok := p.(PathScanner); ok && basePath != "" {
+		items = ps.ScanPath(ctx, s, basePath)
+	} else {
+		items = p.Scan(ctx, s)
+	}
+	s.emit(ctx, Event{Kind: ProviderDone, Items: len(items)})
+	return items
+}
+
+func matchesAny(p Provider, names []string) bool {
+	for _, name := range names {
+		if strings.EqualFold(p.ID(), name) || strings.EqualFold(p.Name(), name) {
+			return true
+		}
+	}
+	return false
+}
+
+func providerIDs() []string {
+	ids := make([]string, 0, len(registry))
+	for _, p := range registry {
+		ids = append(ids, p.ID())
+	}
+	return ids
+}
+
+// funcProvider adapts a plain scan function to the Provider interface
+type funcProvider struct {
+	id          string
+	name        string
+	description string
+	platforms   []string
+	scan        func(ctx context.Context, s *Session) []CleanableItem
+}
+
+func (p funcProvider) ID() string          { return p.id }
+func (p funcProvider) Name() string        { return p.name }
+func (p funcProvider) Description() string { return p.description }
+func (p funcProvider) Platforms() []string { return p.platforms }
+
+func (p funcProvider) Scan(ctx context.Context, s *Session) []CleanableItem {
+	return p.scan(ctx, s)
+}
@@ -0,0 +1,97 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"strings"
+	"unicode"
+
+	"github.com/iml1s/antigravity-cleaner/internal/rules"
+)
+
+// activeRules are the rules path-based scanners consult
+var activeRules = mustBuiltinRules()
+
+func mustBuiltinRules() []rules.Rule {
+	builtin, err := rules.Builtin()
+	if err != nil {
+		// The built-in rules are embedded at compile time, so this is a bug
+		panic(fmt.Sprintf("scanner: %v", err))
+	}
+	return builtin
+}
+
+// UseRules replaces the active rule set. Categories that no registered
+// provider claims get a provider of their own, so user rule files can add
+// new tools without code changes.
+func UseRules(rs []rules.Rule) {
+	activeRules = rs
+	for _, category := range rules.Categories(rs) {
+		if Lookup(category) != nil {
+			continue
+		}
+		category := category
+		Register(funcProvider{
+			id:          slugify(category),
+			name:        category,
+			description: fmt.Sprintf("Clean %s items defined in rule files or custom paths", category),
+			scan: func(ctx context.Context, s *Session) []CleanableItem {
+				return s.scanRules(ctx, category)
+			},
+		})
+	}
+}
+
+// ActiveRules returns the rules currently used for scanning
+func ActiveRules() []rules.Rule {
+	return append([]rules.Rule(nil), activeRules...)
+}
+
+// scanRules returns an item for every existing path of the rules in category
+// whose size is above the rule's minimum
+func (s *Session) scanRules(ctx context.Context, category string) []CleanableItem {
+	var candidates []candidate
+	for _, r := range activeRules {
+		if r.Category != category {
+			continue
+		}
+		paths, errs := r.ExpandPaths(runtime.GOOS)
+		for _, err := range errs {
+			s.report(ctx, r.ID, err)
+		}
+		for _, path := range paths {
+			if !s.exists(ctx, path) {
+				continue
+			}
+			candidates = append(candidates, candidate{
+				item: CleanableItem{
+					Path:        path,
+					Category:    r.Category,
+					Description: r.Description,
+					SafeLevel:   r.Safety,
+					Rule:        r.ID,
+					Processes:   r.Processes,
+				},
+				minSize: r.MinBytes(),
+			})
+		}
+	}
+	return s.measure(ctx, candidates)
+}
+
+// slugify turns a category name into a subcommand-friendly ID
+func slugify(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
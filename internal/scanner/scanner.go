@@ -1,18 +1,122 @@
 package scanner
 
 import (
+	"context"
+	"io/fs"
 	"os"
 	"path/filepath"
-	"runtime"
+	"time"
 )
 
 // CleanableItem represents a directory or file that can be cleaned
 type CleanableItem struct {
-	Path        string
-	Size        int64
+	Path string
+	// Size is the apparent size: the sum of file lengths
+	Size int64
+	// DiskSize is the space deleting the item would reclaim: allocated
+	// blocks, with hardlinked files counted once per scan
+	DiskSize    int64
 	Category    string
 	Description string
 	SafeLevel   string // "safe", "caution", "warning"
+	Rule        string // ID of the rule that produced the item, if any
+	// Incomplete is set when the scan stopped before the whole tree was
+	// measured, making Size a lower bound
+	Incomplete bool
+	// Errors lists paths inside the item that couldn't be read, so its
+	// size may be too small
+	Errors []ScanError
+	// ModTime is the newest modification time of anything in the item
+	ModTime time.Time
+	// AccessTime is the newest access time of any file in the item, or
+	// zero when the filesystem doesn't record access times
+	AccessTime time.Time
+	// Mount is the mount point of the filesystem holding the item, or
+	// empty if it couldn't be resolved
+	Mount string
+	// Root is the directory searched to find the item, for items found by
+	// walking rather than from a rule path
+	Root string
+	// Dev and Ino identify the item as scanned, so the cleaner can tell if
+	// it was replaced since. Both are zero where the platform has no inodes.
+	Dev uint64
+	Ino uint64
+	// Symlink is true when the item itself was a symbolic link
+	Symlink bool
+	// Processes names the programs owning the item, which it shouldn't be
+	// cleaned under
+	Processes []string
+}
+
+// LastUsed returns the later of ModTime and AccessTime
+func (item CleanableItem) LastUsed() time.Time {
+	if item.AccessTime.After(item.ModTime) {
+		return item.AccessTime
+	}
+	return item.ModTime
+}
+
+// Age returns how long ago the item was last used, or zero if unknown
+func (item CleanableItem) Age(now time.Time) time.Duration {
+	if item.LastUsed().IsZero() {
+		return 0
+	}
+	return now.Sub(item.LastUsed())
+}
+
+// Default minimum sizes for items found by walking rather than by rules,
+// which carry their own min_size. Smaller items are hidden.
+const (
+	FlutterBuildMinSize = 100 << 20 // 100 MiB
+	DartToolMinSize     = 50 << 20  // 50 MiB
+	AVDMinSize          = 1 << 30   // 1 GiB
+)
+
+// Result is the outcome of a scan run
+type Result struct {
+	Items []CleanableItem
+	// Errors lists problems that aren't tied to a single item, such as an
+	// unreadable project directory, which may mean items are missing
+	Errors []ScanError
+	// HiddenItems and HiddenBytes count the items left out because they
+	// were below their size threshold
+	HiddenItems int
+	HiddenBytes int64
+	// RecentItems and RecentBytes count the items left out by
+	// Options.OlderThan because they were used too recently
+	RecentItems int
+	RecentBytes int64
+	// Excluded lists the paths left out by exclude patterns and
+	// .agcignore files
+	Excluded []Exclusion
+	// Err is set when the scan was cancelled or timed out, in which case
+	// Items holds whatever was found before it stopped
+	Err error
+}
+
+// Incomplete reports whether the scan stopped early
+func (r Result) Incomplete() bool {
+	return r.Err != nil
+}
+
+// ErrorCount returns the number of provider and item errors
+func (r Result) ErrorCount() int {
+	n := len(r.Errors)
+	for _, item := range r.Items {
+		n += len(item.Errors)
+	}
+	return n
+}
+
+// finish records the provider errors and why the scan stopped early, if it did
+func (r *Result) finish(ctx context.Context, s *Session, providers []Provider) {
+	r.Errors = s.providerErrors(providers)
+	r.HiddenItems = int(s.hiddenItems.Load())
+	r.HiddenBytes = s.hiddenBytes.Load()
+	r.RecentItems = int(s.recentItems.Load())
+	r.RecentBytes = s.recentBytes.Load()
+	r.Excluded = s.exclude.sorted()
+	r.Err = ctx.Err()
 }
 
 // getHomeDir returns the user's home directory
@@ -24,348 +128,226 @@ func getHomeDir() string {
 	return home
 }
 
-// getDirSize calculates the total size of a directory
-func getDirSize(path string) int64 {
-	var size int64
-	_ = filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
-		if err != nil {
-			return nil
-		}
-		if !info.IsDir() {
-			size += info.Size()
-		}
-		return nil
+func init() {
+	Register(funcProvider{
+		id:          "antigravity",
+		name:        "Antigravity",
+		description: "Clean Google Antigravity IDE session recordings, conversations, and caches",
+		scan:        ScanAntigravity,
+	})
+	Register(flutterProvider{funcProvider{
+		id:          "flutter",
+		name:        "Flutter",
+		description: "Clean Flutter project build directories, .dart_tool, and the pub cache",
+		scan: func(ctx context.Context, s *Session) []CleanableItem {
+			return ScanFlutter(ctx, s, "")
+		},
+	}})
+	Register(funcProvider{
+		id:          "xcode",
+		name:        "Xcode",
+		description: "Clean Xcode DerivedData, iOS DeviceSupport, and archives",
+		platforms:   []string{"darwin"},
+		scan:        ScanXcode,
+	})
+	Register(funcProvider{
+		id:          "android",
+		name:        "Android",
+		description: "Clean Gradle caches, Android SDK cache, and AVD images",
+		scan:        ScanAndroid,
 	})
-	return size
+	Register(funcProvider{
+		id:          "vscode",
+		name:        "VS Code",
+		description: "Clean VS Code and Cursor cached data",
+		scan:        ScanVSCode,
+	})
+	Register(simulatorProvider{funcProvider{
+		id:          "simulator",
+		name:        "Simulator",
+		description: "Remove unavailable iOS/watchOS/tvOS simulator runtimes and devices",
+		platforms:   []string{"darwin"},
+		scan:        ScanSimulators,
+	}})
 }
 
-// exists checks if a path exists
-func exists(path string) bool {
-	_, err := os.Stat(path)
-	return err == nil
+// ScanAll scans every provider available on the current OS
+func ScanAll(ctx context.Context, s *Session) Result {
+	return ScanProviders(ctx, s, Available())
 }
 
-// ScanAll scans all supported categories
-func ScanAll() []CleanableItem {
-	var results []CleanableItem
-	results = append(results, ScanAntigravity()...)
-	results = append(results, ScanFlutter("")...)
-	results = append(results, ScanXcode()...)
-	results = append(results, ScanAndroid()...)
-	results = append(results, ScanVSCode()...)
-	return results
+// ScanAntigravity scans for Google Antigravity IDE cleanable items
+func ScanAntigravity(ctx context.Context, s *Session) []CleanableItem {
+	return s.scanRules(ctx, "Antigravity")
 }
 
-// ScanAntigravity scans for Google Antigravity IDE cleanable items
-func ScanAntigravity() []CleanableItem {
-	var results []CleanableItem
-	home := getHomeDir()
+// flutterProvider lets the flutter subcommand override the base path
+type flutterProvider struct {
+	funcProvider
+}
 
-	// Platform-specific paths
-	var paths []struct {
-		path        string
-		category    string
-		description string
-		safeLevel   string
-	}
+func (p flutterProvider) ScanPath(ctx context.Context, s *Session, basePath string) []CleanableItem {
+	return ScanFlutter(ctx, s, basePath)
+}
 
-	switch runtime.GOOS {
-	case "darwin":
-		paths = []struct {
-			path        string
-			category    string
-			description string
-			safeLevel   string
-		}{
-			// ~/.gemini/antigravity - 主要資料
-			{filepath.Join(home, ".gemini", "antigravity", "browser_recordings"), "Antigravity", "Session recordings (screenshots)", "safe"},
-			{filepath.Join(home, ".gemini", "antigravity", "conversations"), "Antigravity", "Conversation history", "caution"},
-			{filepath.Join(home, ".gemini", "antigravity", "brain"), "Antigravity", "AI memory cache", "caution"},
-			{filepath.Join(home, ".gemini", "antigravity", "implicit"), "Antigravity", "Implicit data cache", "safe"},
-
-			// ~/Library/Application Support/Antigravity
-			{filepath.Join(home, "Library", "Application Support", "Antigravity", "CachedData"), "Antigravity", "JS/WASM cached data", "safe"},
-			{filepath.Join(home, "Library", "Application Support", "Antigravity", "Code Cache"), "Antigravity", "Code cache", "safe"},
-			{filepath.Join(home, "Library", "Application Support", "Antigravity", "User", "_extensions-disabled"), "Antigravity", "Disabled extensions backup", "safe"},
-			{filepath.Join(home, "Library", "Application Support", "Antigravity", "DawnWebGPUCache"), "Antigravity", "WebGPU cache", "safe"},
-			{filepath.Join(home, "Library", "Application Support", "Antigravity", "DawnGraphiteCache"), "Antigravity", "Graphite cache", "safe"},
-			{filepath.Join(home, "Library", "Application Support", "Antigravity", "User", "workspaceStorage"), "Antigravity", "Workspace storage", "caution"},
-
-			// ~/.antigravity
-			{filepath.Join(home, ".antigravity", "extensions"), "Antigravity", "Old extension versions", "safe"},
-		}
-	case "windows":
-		appData := os.Getenv("APPDATA")
-		localAppData := os.Getenv("LOCALAPPDATA")
-		paths = []struct {
-			path        string
-			category    string
-			description string
-			safeLevel   string
-		}{
-			{filepath.Join(home, ".gemini", "antigravity", "browser_recordings"), "Antigravity", "Session recordings", "safe"},
-			{filepath.Join(home, ".gemini", "antigravity", "conversations"), "Antigravity", "Conversation history", "caution"},
-			{filepath.Join(home, ".gemini", "antigravity", "brain"), "Antigravity", "AI memory cache", "caution"},
-			{filepath.Join(appData, "Antigravity", "CachedData"), "Antigravity", "Cached data", "safe"},
-			{filepath.Join(appData, "Antigravity", "Code Cache"), "Antigravity", "Code cache", "safe"},
-			{filepath.Join(localAppData, "Antigravity", "CachedData"), "Antigravity", "Local cached data", "safe"},
+// ScanFlutter scans for Flutter project build directories under basePath,
+// or under the session's project roots when basePath is empty
+func ScanFlutter(ctx context.Context, s *Session, basePath string) []CleanableItem {
+	var candidates []candidate
+	if basePath != "" {
+		// The cleaner only accepts absolute paths, so a relative --path
+		// is resolved against the working directory here
+		if abs, err := filepath.Abs(basePath); err == nil {
+			basePath = abs
 		}
-	case "linux":
-		configDir := filepath.Join(home, ".config")
-		paths = []struct {
-			path        string
-			category    string
-			description string
-			safeLevel   string
-		}{
-			{filepath.Join(home, ".gemini", "antigravity", "browser_recordings"), "Antigravity", "Session recordings", "safe"},
-			{filepath.Join(home, ".gemini", "antigravity", "conversations"), "Antigravity", "Conversation history", "caution"},
-			{filepath.Join(home, ".gemini", "antigravity", "brain"), "Antigravity", "AI memory cache", "caution"},
-			{filepath.Join(configDir, "Antigravity", "CachedData"), "Antigravity", "Cached data", "safe"},
-			{filepath.Join(configDir, "Antigravity", "Code Cache"), "Antigravity", "Code cache", "safe"},
+		candidates = findFlutterProjects(ctx, s, basePath, true)
+	} else {
+		for _, root := range s.projectRoots() {
+			candidates = append(candidates, findFlutterProjects(ctx, s, root.path, root.reportMissing)...)
 		}
 	}
 
-	for _, p := range paths {
-		if exists(p.path) {
-			size := getDirSize(p.path)
-			if size > 0 {
-				results = append(results, CleanableItem{
-					Path:        p.path,
-					Size:        size,
-					Category:    p.category,
-					Description: p.description,
-					SafeLevel:   p.safeLevel,
-				})
-			}
-		}
-	}
+	results := s.measure(ctx, candidates)
+
+	// Global Dart/Flutter caches
+	results = append(results, s.scanRules(ctx, "Flutter")...)
 
 	return results
 }
 
-// ScanFlutter scans for Flutter project build directories
-func ScanFlutter(basePath string) []CleanableItem {
-	var results []CleanableItem
-	home := getHomeDir()
+// projectRoot is a directory searched for projects
+type projectRoot struct {
+	path string
+	// reportMissing is false for roots the user didn't ask for
+	reportMissing bool
+}
 
-	if basePath == "" {
-		basePath = filepath.Join(home, "Documents")
+// projectRoots returns the configured roots, or ~/Documents when there are
+// none, followed by the workspaces. Roots inside another root are left out
+// so no project is found twice.
+func (s *Session) projectRoots() []projectRoot {
+	var all []projectRoot
+	for _, root := range s.roots {
+		all = append(all, projectRoot{root, true})
+	}
+	if len(all) == 0 {
+		all = append(all, projectRoot{filepath.Join(getHomeDir(), "Documents"), false})
+	}
+	for _, ws := range s.workspaces {
+		all = append(all, projectRoot{ws, false})
 	}
 
-	// Find Flutter projects by looking for pubspec.yaml
-	_ = filepath.Walk(basePath, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return nil
+	var result []projectRoot
+	for i, root := range all {
+		nested := false
+		for j, other := range all {
+			// Of two equal roots, the first one is kept
+			if i != j && within(root.path, other.path) && (filepath.Clean(root.path) != filepath.Clean(other.path) || j < i) {
+				nested = true
+				break
+			}
+		}
+		if !nested {
+			result = append(result, root)
+		}
+	}
+	return result
+}
+
+// findFlutterProjects walks basePath for Flutter projects by looking for
+// pubspec.yaml. The walk only collects candidates; sizing them happens in
+// parallel afterwards. A missing default root just means there's nothing
+// to scan, so it's only reported when the user asked for basePath.
+func findFlutterProjects(ctx context.Context, s *Session, basePath string, reportMissing bool) []candidate {
+	var candidates []candidate
+	s.walkProjects(ctx, basePath, reportMissing, func(path string, info fs.FileInfo) error {
+		s.emit(ctx, Event{Kind: DirEntered, Path: path})
+
+		// Look for .dart_tool directories
+		if info.Name() == ".dart_tool" {
+			parent := filepath.Dir(path)
+			candidates = append(candidates, candidate{
+				item: CleanableItem{
+					Path:        path,
+					Category:    "Flutter",
+					Description: ".dart_tool: " + filepath.Base(parent),
+					SafeLevel:   "safe",
+					Root:        basePath,
+				},
+				minSize: DartToolMinSize,
+			})
+			return filepath.SkipDir
 		}
 
 		// Skip hidden directories and node_modules
-		if info.IsDir() && (info.Name()[0] == '.' || info.Name() == "node_modules") {
+		if info.Name()[0] == '.' || info.Name() == "node_modules" {
 			return filepath.SkipDir
 		}
 
 		// Look for build directories in Flutter projects
-		if info.IsDir() && info.Name() == "build" {
+		if info.Name() == "build" {
 			// Check if parent has pubspec.yaml (Flutter project)
 			parent := filepath.Dir(path)
-			if exists(filepath.Join(parent, "pubspec.yaml")) {
-				size := getDirSize(path)
-				if size > 100*1024*1024 { // Only show if > 100MB
-					results = append(results, CleanableItem{
+			if s.exists(ctx, filepath.Join(parent, "pubspec.yaml")) {
+				candidates = append(candidates, candidate{
+					item: CleanableItem{
 						Path:        path,
-						Size:        size,
 						Category:    "Flutter",
 						Description: "Build directory: " + filepath.Base(parent),
 						SafeLevel:   "safe",
-					})
-				}
-			}
-		}
-
-		// Look for .dart_tool directories
-		if info.IsDir() && info.Name() == ".dart_tool" {
-			size := getDirSize(path)
-			if size > 50*1024*1024 { // Only show if > 50MB
-				parent := filepath.Dir(path)
-				results = append(results, CleanableItem{
-					Path:        path,
-					Size:        size,
-					Category:    "Flutter",
-					Description: ".dart_tool: " + filepath.Base(parent),
-					SafeLevel:   "safe",
+						Root:        basePath,
+					},
+					minSize: FlutterBuildMinSize,
 				})
+				return filepath.SkipDir
 			}
 		}
 
 		return nil
 	})
-
-	// Global Dart/Flutter caches
-	pubCache := filepath.Join(home, ".pub-cache")
-	if exists(pubCache) {
-		size := getDirSize(pubCache)
-		if size > 100*1024*1024 {
-			results = append(results, CleanableItem{
-				Path:        pubCache,
-				Size:        size,
-				Category:    "Flutter",
-				Description: "Pub package cache",
-				SafeLevel:   "caution",
-			})
-		}
-	}
-
-	return results
+	return candidates
 }
 
 // ScanXcode scans for Xcode cleanable items (macOS only)
-func ScanXcode() []CleanableItem {
-	var results []CleanableItem
-
-	if runtime.GOOS != "darwin" {
-		return results
-	}
-
-	home := getHomeDir()
-
-	paths := []struct {
-		path        string
-		description string
-		safeLevel   string
-	}{
-		{filepath.Join(home, "Library", "Developer", "Xcode", "DerivedData"), "Xcode DerivedData", "safe"},
-		{filepath.Join(home, "Library", "Developer", "Xcode", "iOS DeviceSupport"), "iOS DeviceSupport", "safe"},
-		{filepath.Join(home, "Library", "Developer", "Xcode", "watchOS DeviceSupport"), "watchOS DeviceSupport", "safe"},
-		{filepath.Join(home, "Library", "Developer", "Xcode", "Archives"), "Xcode Archives", "caution"},
-		{filepath.Join(home, "Library", "Developer", "CoreSimulator", "Caches"), "Simulator Caches", "safe"},
-	}
-
-	for _, p := range paths {
-		if exists(p.path) {
-			size := getDirSize(p.path)
-			if size > 100*1024*1024 { // Only show if > 100MB
-				results = append(results, CleanableItem{
-					Path:        p.path,
-					Size:        size,
-					Category:    "Xcode",
-					Description: p.description,
-					SafeLevel:   p.safeLevel,
-				})
-			}
-		}
-	}
-
-	return results
+func ScanXcode(ctx context.Context, s *Session) []CleanableItem {
+	return s.scanRules(ctx, "Xcode")
 }
 
 // ScanAndroid scans for Android development cleanable items
-func ScanAndroid() []CleanableItem {
-	var results []CleanableItem
+func ScanAndroid(ctx context.Context, s *Session) []CleanableItem {
+	results := s.scanRules(ctx, "Android")
 	home := getHomeDir()
 
-	paths := []struct {
-		path        string
-		description string
-		safeLevel   string
-	}{
-		{filepath.Join(home, ".gradle", "caches"), "Gradle caches", "safe"},
-		{filepath.Join(home, ".gradle", "wrapper", "dists"), "Gradle distributions", "caution"},
-		{filepath.Join(home, ".android", "cache"), "Android SDK cache", "safe"},
-	}
-
-	for _, p := range paths {
-		if exists(p.path) {
-			size := getDirSize(p.path)
-			if size > 100*1024*1024 {
-				results = append(results, CleanableItem{
-					Path:        p.path,
-					Size:        size,
-					Category:    "Android",
-					Description: p.description,
-					SafeLevel:   p.safeLevel,
-				})
-			}
-		}
-	}
-
 	// Scan for AVD images
+	var candidates []candidate
 	avdPath := filepath.Join(home, ".android", "avd")
-	if exists(avdPath) {
+	if s.exists(ctx, avdPath) {
 		entries, err := os.ReadDir(avdPath)
-		if err == nil {
+		if err != nil {
+			s.report(ctx, avdPath, err)
+		} else {
 			for _, entry := range entries {
 				if entry.IsDir() && filepath.Ext(entry.Name()) == ".avd" {
-					fullPath := filepath.Join(avdPath, entry.Name())
-					size := getDirSize(fullPath)
-					if size > 1024*1024*1024 { // Only show if > 1GB
-						results = append(results, CleanableItem{
-							Path:        fullPath,
-							Size:        size,
+					candidates = append(candidates, candidate{
+						item: CleanableItem{
+							Path:        filepath.Join(avdPath, entry.Name()),
 							Category:    "Android",
 							Description: "AVD: " + entry.Name(),
 							SafeLevel:   "warning",
-						})
-					}
-				}
-			}
-		}
-	}
-
-	return results
-}
-
-// ScanVSCode scans for VS Code and variants cleanable items
-func ScanVSCode() []CleanableItem {
-	var results []CleanableItem
-	home := getHomeDir()
-
-	var basePaths []string
-
-	switch runtime.GOOS {
-	case "darwin":
-		basePaths = []string{
-			filepath.Join(home, "Library", "Application Support", "Code"),
-			filepath.Join(home, "Library", "Application Support", "Cursor"),
-		}
-	case "windows":
-		appData := os.Getenv("APPDATA")
-		basePaths = []string{
-			filepath.Join(appData, "Code"),
-			filepath.Join(appData, "Cursor"),
-		}
-	case "linux":
-		configDir := filepath.Join(home, ".config")
-		basePaths = []string{
-			filepath.Join(configDir, "Code"),
-			filepath.Join(configDir, "Cursor"),
-		}
-	}
-
-	cacheSubdirs := []string{"CachedData", "Code Cache", "CachedExtensions", "CachedExtensionVSIXs"}
-
-	for _, basePath := range basePaths {
-		for _, subdir := range cacheSubdirs {
-			path := filepath.Join(basePath, subdir)
-			if exists(path) {
-				size := getDirSize(path)
-				if size > 50*1024*1024 {
-					results = append(results, CleanableItem{
-						Path:        path,
-						Size:        size,
-						Category:    "VS Code",
-						Description: filepath.Base(basePath) + " " + subdir,
-						SafeLevel:   "safe",
+							Root:        avdPath,
+							Processes:   []string{"emulator", "qemu-system-x86_64", "qemu-system-aarch64"},
+						},
+						minSize: AVDMinSize,
 					})
 				}
 			}
 		}
 	}
 
-	return results
+	return append(results, s.measure(ctx, candidates)...)
 }
 
-// ScanSimulators scans for old simulator runtimes
-func ScanSimulators() []CleanableItem {
-	// This is handled specially via xcrun simctl
-	return []CleanableItem{}
+// ScanVSCode scans for VS Code and variants cleanable items
+func ScanVSCode(ctx context.Context, s *Session) []CleanableItem {
+	return s.scanRules(ctx, "VS Code")
 }
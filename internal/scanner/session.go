@@ -0,0 +1,158 @@
+package scanner
+
+import (
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/iml1s/antigravity-cleaner/internal/fsinfo"
+	"github.com/iml1s/antigravity-cleaner/internal/sizecache"
+)
+
+// Options controls a scan run
+type Options struct {
+	// Jobs bounds how many goroutines scan at once. Values below 1 mean
+	// runtime.NumCPU().
+	Jobs int
+	// Progress, if set, receives scan events. It's called from many
+	// goroutines at once and must be safe for concurrent use.
+	Progress func(Event)
+	// Thresholds overrides the minimum item sizes of providers and rules
+	Thresholds Thresholds
+	// Roots are the directories project-walking providers search when no
+	// base path is given. Empty means ~/Documents.
+	Roots []string
+	// Workspaces are project folders found outside the configuration,
+	// such as recently opened editor workspaces. They are searched as well
+	// as Roots, and silently skipped when missing.
+	Workspaces []string
+	// Exclude lists paths that are never walked into or reported. Entries
+	// are absolute paths or globs, or gitignore-style patterns matched at
+	// any depth. Items containing a literal excluded path are dropped too.
+	Exclude []string
+	// OlderThan, when positive, drops items used more recently than this
+	OlderThan time.Duration
+	// Cache, if set, supplies and records directory listings so unchanged
+	// subtrees aren't read again. The caller saves it after the scan.
+	Cache *sizecache.Cache
+}
+
+// Thresholds overrides the minimum size an item needs to be reported
+type Thresholds struct {
+	// Global, when set, replaces every other threshold
+	Global *int64
+	// Categories maps a category name (matched case-insensitively) to the
+	// minimum size for its items
+	Categories map[string]int64
+}
+
+// Session holds the state shared by every provider during one scan run
+type Session struct {
+	jobs int
+	// sem holds a token for every goroutine running besides the caller
+	sem chan struct{}
+
+	progress func(Event)
+	counted  atomic.Int64
+	cache    *sizecache.Cache
+
+	// seen records hardlinked files already counted in this session, so
+	// a file linked into several trees only counts towards one of them
+	seenMu sync.Mutex
+	seen   map[fileID]bool
+
+	roots      []string
+	workspaces []string
+	exclude    *exclusions
+	mounts     *fsinfo.Resolver
+
+	// errs holds errors not tied to a single item
+	errs errorList
+
+	thresholds  Thresholds
+	hiddenItems atomic.Int64
+	hiddenBytes atomic.Int64
+
+	now         time.Time
+	olderThan   time.Duration
+	recentItems atomic.Int64
+	recentBytes atomic.Int64
+}
+
+// NewSession prepares a scan run
+func NewSession(opts Options) *Session {
+	jobs := opts.Jobs
+	if jobs < 1 {
+		jobs = runtime.NumCPU()
+	}
+	return &Session{
+		jobs:       jobs,
+		sem:        make(chan struct{}, jobs-1),
+		progress:   opts.Progress,
+		cache:      opts.Cache,
+		roots:      opts.Roots,
+		workspaces: opts.Workspaces,
+		exclude:    newExclusions(opts.Exclude),
+		mounts:     fsinfo.NewResolver(),
+		thresholds: opts.Thresholds,
+		now:        time.Now(),
+		olderThan:  opts.OlderThan,
+		seen:       make(map[fileID]bool),
+	}
+}
+
+// threshold returns the minimum size for an item in category, given the
+// provider's default
+func (s *Session) threshold(category string, defaultMin int64) int64 {
+	if s.thresholds.Global != nil {
+		return *s.thresholds.Global
+	}
+	for name, min := range s.thresholds.Categories {
+		if strings.EqualFold(name, category) {
+			return min
+		}
+	}
+	return defaultMin
+}
+
+// Jobs returns the concurrency limit of the session
+func (s *Session) Jobs() int {
+	return s.jobs
+}
+
+// group runs functions on extra goroutines while the session has spare
+// tokens and inline otherwise. Because it never blocks waiting for a token,
+// groups can nest (a provider sizing directories that fan out further)
+// without deadlocking, and total concurrency stays within Jobs.
+type group struct {
+	s  *Session
+	wg sync.WaitGroup
+}
+
+func (s *Session) group() *group {
+	return &group{s: s}
+}
+
+// Go runs fn concurrently if a token is free, otherwise before returning
+func (g *group) Go(fn func()) {
+	select {
+	case g.s.sem <- struct{}{}:
+		g.wg.Add(1)
+		go func() {
+			defer func() {
+				<-g.s.sem
+				g.wg.Done()
+			}()
+			fn()
+		}()
+	default:
+		fn()
+	}
+}
+
+// Wait blocks until every function passed to Go has returned
+func (g *group) Wait() {
+	g.wg.Wait()
+}
@@ -0,0 +1,179 @@
+//go:build !windows
+
+package scanner
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testTree builds a tree whose three top-level directories share a
+// hardlinked file, hold symlinks that must not be followed, and, in
+// "locked", an unreadable subdirectory
+func testTree(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	write := func(rel string, size int) {
+		path := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(strings.Repeat("x", size)), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("a/shared", 256<<10)
+	write("a/one", 10_000)
+	write("a/deep/er/two", 20_000)
+	write("b/three", 30_000)
+	write("c/four", 40_000)
+	write("c/locked/hidden", 50_000)
+	for _, link := range []string{"a/deep/shared", "b/shared", "c/shared"} {
+		if err := os.Link(filepath.Join(root, "a/shared"), filepath.Join(root, link)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(filepath.Join(root, "a"), filepath.Join(root, "b/to-a")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("/", filepath.Join(root, "c/to-root")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(filepath.Join(root, "c/locked"), 0); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chmod(filepath.Join(root, "c/locked"), 0o755) })
+	return root
+}
+
+// treeProviders returns one provider per top-level directory of root
+func treeProviders(root string) []Provider {
+	var providers []Provider
+	for _, name := range []string{"a", "b", "c"} {
+		path := filepath.Join(root, name)
+		providers = append(providers, funcProvider{
+			id:   "test-" + name,
+			name: "Test " + name,
+			scan: func(ctx context.Context, s *Session) []CleanableItem {
+				return s.measure(ctx, []candidate{{item: CleanableItem{Path: path, Category: "Test"}}})
+			},
+		})
+	}
+	return providers
+}
+
+// diskUsage totals the readable tree at root the slow way, counting each
+// inode once and never following symlinks
+func diskUsage(t *testing.T, root string) int64 {
+	t.Helper()
+	seen := make(map[fileID]bool)
+	var total int64
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		info, err := os.Lstat(path)
+		if err != nil {
+			return err
+		}
+		disk, id, linked := fileUsage(info)
+		if linked {
+			if seen[id] {
+				return nil
+			}
+			seen[id] = true
+		}
+		total += disk
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return total
+}
+
+func TestScanProvidersConcurrent(t *testing.T) {
+	root := testTree(t)
+	want := diskUsage(t, root)
+	// The test root itself isn't an item
+	info, err := os.Lstat(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rootDisk, _, _ := fileUsage(info)
+	want -= rootDisk
+
+	zero := int64(0)
+	var sizes map[string]int64
+	for run := 0; run < 20; run++ {
+		s := NewSession(Options{Jobs: 8, Thresholds: Thresholds{Global: &zero}})
+		result := ScanProviders(context.Background(), s, treeProviders(root))
+		if len(result.Items) != 3 {
+			t.Fatalf("run %d: got %d items, want 3", run, len(result.Items))
+		}
+
+		var total int64
+		got := make(map[string]int64)
+		for i, item := range result.Items {
+			if wantPath := filepath.Join(root, []string{"a", "b", "c"}[i]); item.Path != wantPath {
+				t.Fatalf("run %d: item %d is %s, want %s", run, i, item.Path, wantPath)
+			}
+			total += item.DiskSize
+			got[item.Path] = item.Size
+		}
+		// The hardlinked file counts towards exactly one item, whichever
+		// walker reached it first
+		if total != want {
+			t.Fatalf("run %d: total disk size %d, want %d", run, total, want)
+		}
+		if sizes == nil {
+			sizes = got
+			continue
+		}
+		for path, size := range got {
+			if sizes[path] != size {
+				t.Fatalf("run %d: %s measured %d bytes, earlier %d", run, path, size, sizes[path])
+			}
+		}
+	}
+
+	// Apparent sizes count every link but never a symlink's target
+	a := int64(256<<10)*2 + 10_000 + 20_000
+	b := int64(256<<10) + 30_000 + int64(len(filepath.Join(root, "a")))
+	if got := sizes[filepath.Join(root, "a")]; got != a {
+		t.Errorf("a: apparent size %d, want %d", got, a)
+	}
+	if got := sizes[filepath.Join(root, "b")]; got != b {
+		t.Errorf("b: apparent size %d, want %d", got, b)
+	}
+}
+
+func TestScanReportsUnreadableDirs(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root can read any directory")
+	}
+	root := testTree(t)
+	zero := int64(0)
+	s := NewSession(Options{Jobs: 4, Thresholds: Thresholds{Global: &zero}})
+	result := ScanProviders(context.Background(), s, treeProviders(root))
+
+	locked := filepath.Join(root, "c", "locked")
+	for _, item := range result.Items {
+		if item.Path != filepath.Join(root, "c") {
+			if len(item.Errors) != 0 {
+				t.Errorf("%s: unexpected errors %v", item.Path, item.Errors)
+			}
+			continue
+		}
+		if len(item.Errors) != 1 || item.Errors[0].Path != locked {
+			t.Errorf("%s: errors %v, want one for %s", item.Path, item.Errors, locked)
+		}
+		if want := int64(256<<10) + 40_000 + 1; item.Size != want {
+			t.Errorf("%s: apparent size %d, want %d", item.Path, item.Size, want)
+		}
+	}
+}
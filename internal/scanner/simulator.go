@@ -0,0 +1,35 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// simulatorProvider removes simulator runtimes through xcrun simctl instead
+// of deleting paths directly
+type simulatorProvider struct {
+	funcProvider
+}
+
+// ScanSimulators scans for old simulator runtimes
+func ScanSimulators(ctx context.Context, s *Session) []CleanableItem {
+	// This is handled specially via xcrun simctl
+	return []CleanableItem{}
+}
+
+// Clean removes unavailable simulator devices, then each selected runtime.
+// Failing to remove the unavailable devices doesn't affect the runtimes, so
+// it's only warned about.
+func (p simulatorProvider) Clean(items []CleanableItem) []error {
+	if err := exec.Command("xcrun", "simctl", "delete", "unavailable").Run(); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Could not remove unavailable simulator devices: %v\n", err)
+	}
+
+	errs := make([]error, len(items))
+	for i, item := range items {
+		errs[i] = exec.Command("xcrun", "simctl", "runtime", "delete", item.Path).Run()
+	}
+	return errs
+}
@@ -0,0 +1,27 @@
+//go:build !windows
+
+package scanner
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileID identifies a file across hardlinks
+type fileID struct {
+	dev uint64
+	ino uint64
+}
+
+// fileUsage returns the space allocated to a file, which is smaller than
+// its length for sparse files, and its identity for hardlink dedupe
+func fileUsage(info os.FileInfo) (disk int64, id fileID, linked bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return info.Size(), fileID{}, false
+	}
+	id = fileID{dev: uint64(st.Dev), ino: uint64(st.Ino)}
+	// st_blocks is always in 512-byte units, whatever the filesystem's
+	// block size
+	return int64(st.Blocks) * 512, id, !info.IsDir() && st.Nlink > 1
+}
@@ -0,0 +1,20 @@
+//go:build windows
+
+package scanner
+
+import "os"
+
+// fileID identifies a file across hardlinks
+type fileID struct {
+	dev uint64
+	ino uint64
+}
+
+// fileUsage falls back to the file length on Windows, where allocation
+// size and link count aren't available from a directory listing
+func fileUsage(info os.FileInfo) (disk int64, id fileID, linked bool) {
+	if info.IsDir() {
+		return 0, fileID{}, false
+	}
+	return info.Size(), fileID{}, false
+}
@@ -0,0 +1,290 @@
+package scanner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/iml1s/antigravity-cleaner/internal/sizecache"
+)
+
+// candidate is an item whose size hasn't been measured yet
+type candidate struct {
+	item CleanableItem
+	// minSize is the provider or rule default, which Options.Thresholds
+	// can override
+	minSize int64
+}
+
+// usage is the measured size of a tree
+type usage struct {
+	// apparent is the sum of file lengths
+	apparent int64
+	// disk is the space allocated to the tree, counting each hardlinked
+	// file once per session
+	disk int64
+	// newestMod is the latest mtime in the tree and newestAccess the
+	// latest atime of any file in it, in Unix nanoseconds. Directory atimes
+	// are left out because listing a directory, as scanning does, updates it.
+	newestMod    int64
+	newestAccess int64
+}
+
+// tally accumulates a tree's usage across walker goroutines
+type tally struct {
+	apparent     atomic.Int64
+	disk         atomic.Int64
+	newestMod    atomic.Int64
+	newestAccess atomic.Int64
+	truncated    atomic.Bool
+	errs         errorList
+}
+
+// storeMax raises v to n if n is larger
+func storeMax(v *atomic.Int64, n int64) {
+	for {
+		old := v.Load()
+		if n <= old || v.CompareAndSwap(old, n) {
+			return
+		}
+	}
+}
+
+// measure sizes the candidates in parallel and returns, in the original
+// order, those whose on-disk size is above their threshold. Candidates whose
+// walk was cut short by ctx are marked Incomplete.
+func (s *Session) measure(ctx context.Context, candidates []candidate) []CleanableItem {
+	kept := candidates[:0:0]
+	for _, c := range candidates {
+		if reason := s.touchesExcluded(c.item.Path); reason != "" {
+			s.exclude.record(c.item.Path, reason)
+			continue
+		}
+		kept = append(kept, c)
+	}
+	candidates = kept
+
+	sizes := make([]usage, len(candidates))
+	complete := make([]bool, len(candidates))
+	errs := make([][]ScanError, len(candidates))
+	g := s.group()
+	for i := range candidates {
+		i := i
+		g.Go(func() { sizes[i], complete[i], errs[i] = s.dirSize(ctx, candidates[i].item.Path) })
+	}
+	g.Wait()
+
+	var results []CleanableItem
+	for i, c := range candidates {
+		if sizes[i].disk == 0 {
+			continue
+		}
+		if sizes[i].disk <= s.threshold(c.item.Category, c.minSize) {
+			s.hiddenItems.Add(1)
+			s.hiddenBytes.Add(sizes[i].disk)
+			continue
+		}
+
+		item := c.item
+		item.Size = sizes[i].apparent
+		item.DiskSize = sizes[i].disk
+		item.ModTime = fromUnixNano(sizes[i].newestMod)
+		item.AccessTime = fromUnixNano(sizes[i].newestAccess)
+		item.Incomplete = !complete[i]
+		item.Errors = errs[i]
+		item.Mount = s.mounts.Mount(item.Path)
+		if info, err := os.Lstat(item.Path); err == nil {
+			item.Dev, item.Ino = Identity(info)
+			item.Symlink = info.Mode()&os.ModeSymlink != 0
+		}
+
+		// An incomplete walk may have missed newer files, so it can't prove
+		// the item is old enough
+		if s.olderThan > 0 && (item.Incomplete || s.now.Sub(item.LastUsed()) < s.olderThan) {
+			s.recentItems.Add(1)
+			s.recentBytes.Add(item.DiskSize)
+			continue
+		}
+		results = append(results, item)
+		s.emit(ctx, Event{Kind: ItemFound, Path: item.Path, Item: &item})
+	}
+	return results
+}
+
+// dirSize calculates the total size of a directory, walking subdirectories
+// in parallel. complete is false when ctx ended the walk early, in which
+// case the sizes are lower bounds; errs lists what couldn't be read.
+func (s *Session) dirSize(ctx context.Context, path string) (size usage, complete bool, errs []ScanError) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return usage{}, true, []ScanError{newScanError(ctx, path, err)}
+	}
+
+	var t tally
+	disk, id, linked := fileUsage(info)
+	if linked && !s.firstSighting(id) {
+		disk = 0
+	}
+	t.disk.Add(disk)
+	t.newestMod.Store(info.ModTime().UnixNano())
+
+	if info.IsDir() {
+		g := s.group()
+		s.walkSize(ctx, g, path, info, &t)
+		g.Wait()
+	} else {
+		t.apparent.Add(info.Size())
+		t.newestAccess.Store(unixNano(fileAtime(info)))
+		s.count(ctx, info.Size())
+	}
+
+	size = usage{
+		apparent:  t.apparent.Load(),
+		disk:      t.disk.Load(),
+		newestMod: t.newestMod.Load(),
+	}
+	if atimeReliable(path) {
+		size.newestAccess = t.newestAccess.Load()
+	}
+	return size, !t.truncated.Load(), t.errs.sorted()
+}
+
+// walkSize adds the contents of dir to t. Directories whose cached listing
+// is still valid are not read again; only their subdirectories are checked.
+func (s *Session) walkSize(ctx context.Context, g *group, dir string, info os.FileInfo, t *tally) {
+	if ctx.Err() != nil {
+		t.truncated.Store(true)
+		return
+	}
+	s.emit(ctx, Event{Kind: DirEntered, Path: dir})
+
+	// Cached timestamps miss files modified or read in place, so filtering
+	// by age always needs a fresh listing
+	sig := signature(info)
+	if s.cache != nil && s.olderThan == 0 {
+		if entry, ok := s.cache.Lookup(dir, sig); ok {
+			s.addEntry(ctx, t, entry)
+			for _, name := range entry.Subdirs {
+				path := filepath.Join(dir, name)
+				subInfo, err := os.Lstat(path)
+				if err != nil || !subInfo.IsDir() {
+					continue
+				}
+				g.Go(func() { s.walkSize(ctx, g, path, subInfo, t) })
+			}
+			return
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.errs.add(newScanError(ctx, dir, err))
+		return
+	}
+
+	entry := sizecache.Entry{Sig: sig}
+	cacheable := true
+	for _, de := range entries {
+		childInfo, err := de.Info()
+		if err != nil {
+			// Vanished since the listing or unreadable; don't cache a
+			// listing that's missing it
+			t.errs.add(newScanError(ctx, filepath.Join(dir, de.Name()), err))
+			cacheable = false
+			continue
+		}
+		disk, id, linked := fileUsage(childInfo)
+		if linked {
+			entry.Links = append(entry.Links, sizecache.Link{Dev: id.dev, Ino: id.ino, Disk: disk})
+		} else {
+			entry.Disk += disk
+		}
+		if mod := childInfo.ModTime().UnixNano(); mod > entry.NewestMod {
+			entry.NewestMod = mod
+		}
+
+		if !childInfo.IsDir() {
+			entry.Apparent += childInfo.Size()
+			if access := unixNano(fileAtime(childInfo)); access > entry.NewestAccess {
+				entry.NewestAccess = access
+			}
+			continue
+		}
+		entry.Subdirs = append(entry.Subdirs, de.Name())
+		path := filepath.Join(dir, de.Name())
+		g.Go(func() { s.walkSize(ctx, g, path, childInfo, t) })
+	}
+
+	s.addEntry(ctx, t, entry)
+	if s.cache != nil && cacheable && !sig.Racy(s.now) {
+		s.cache.Store(dir, entry)
+	}
+}
+
+// addEntry counts a directory's direct contents towards t
+func (s *Session) addEntry(ctx context.Context, t *tally, entry sizecache.Entry) {
+	disk := entry.Disk
+	for _, link := range entry.Links {
+		if s.firstSighting(fileID{dev: link.Dev, ino: link.Ino}) {
+			disk += link.Disk
+		}
+	}
+	t.apparent.Add(entry.Apparent)
+	t.disk.Add(disk)
+	storeMax(&t.newestMod, entry.NewestMod)
+	storeMax(&t.newestAccess, entry.NewestAccess)
+	s.count(ctx, entry.Apparent)
+}
+
+// unixNano converts t to Unix nanoseconds, keeping zero for unknown times
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+// Identity returns the device and inode of info, or zeros where the
+// platform doesn't provide them
+func Identity(info os.FileInfo) (dev, ino uint64) {
+	_, id, _ := fileUsage(info)
+	return id.dev, id.ino
+}
+
+// signature identifies the current state of a directory for the cache
+func signature(info os.FileInfo) sizecache.Signature {
+	_, id, _ := fileUsage(info)
+	return sizecache.Signature{ModTime: info.ModTime().UnixNano(), Dev: id.dev, Ino: id.ino}
+}
+
+// firstSighting reports whether a hardlinked file is being counted for the
+// first time in this session. Which tree gets the bytes depends on walk
+// order, but the session total is always exact.
+func (s *Session) firstSighting(id fileID) bool {
+	s.seenMu.Lock()
+	defer s.seenMu.Unlock()
+	if s.seen[id] {
+		return false
+	}
+	s.seen[id] = true
+	return true
+}
+
+// count adds n to the session's running byte total and reports it
+func (s *Session) count(ctx context.Context, n int64) {
+	if n == 0 {
+		return
+	}
+	total := s.counted.Add(n)
+	s.emit(ctx, Event{Kind: BytesCounted, Bytes: total})
+}
+
+// fromUnixNano is the inverse of unixNano
+func fromUnixNano(n int64) time.Time {
+	if n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, n)
+}
@@ -0,0 +1,244 @@
+// Package sizecache persists per-directory size totals between runs so
+// unchanged subtrees don't have to be listed again.
+//
+// Each entry covers the direct contents of one directory and is valid only
+// while the directory's mtime, device and inode match. Creating, deleting or
+// renaming anything in a directory updates its mtime, so an entry can't
+// outlive a deletion inside it. A change made in the same clock tick as the
+// listing leaves the mtime unchanged, though, so as with git's racily clean
+// index entries, directories modified around the scan start are never
+// cached (see Racy). Files that grow in place without their
+// directory changing, and files read without their directory changing, are
+// not detected, so the cached sizes and timestamps of such directories lag
+// behind; `agc cache clear` or --no-cache forces a full walk.
+package sizecache
+
+import (
+	"encoding/gob"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/iml1s/antigravity-cleaner/internal/xdg"
+)
+
+// version is bumped whenever Entry changes shape; older files are ignored
+const version = 3
+
+// maxIdle is how long an entry survives without being used
+const maxIdle = 30 * 24 * time.Hour
+
+// racyWindow is the coarsest mtime resolution expected, FAT's two seconds
+const racyWindow = 2 * time.Second
+
+// Signature is the cheap validity check for an entry
+type Signature struct {
+	ModTime int64 // directory mtime in nanoseconds
+	Dev     uint64
+	Ino     uint64
+}
+
+// Racy reports whether a directory listed by a scan that began at start
+// may change again without its mtime moving: its mtime is within the
+// filesystem's timestamp resolution of start, or later. Such listings
+// must not be stored.
+func (s Signature) Racy(start time.Time) bool {
+	return s.ModTime > start.Add(-racyWindow).UnixNano()
+}
+
+// Link is a hardlinked file, kept separately so scans can still count each
+// inode only once
+type Link struct {
+	Dev  uint64
+	Ino  uint64
+	Disk int64
+}
+
+// Entry is the cached listing of one directory
+type Entry struct {
+	Sig Signature
+	// Apparent and Disk total the directory's direct children, excluding
+	// the on-disk size of hardlinked files, which are listed in Links
+	Apparent int64
+	Disk     int64
+	Links    []Link
+	// NewestMod and NewestAccess are the latest mtime of any child and
+	// the latest atime of any child file, in Unix nanoseconds
+	NewestMod    int64
+	NewestAccess int64
+	// Subdirs names the child directories to descend into
+	Subdirs []string
+	// Used is when the entry was last stored or looked up (Unix seconds)
+	Used int64
+}
+
+// Cache is an in-memory view of the cache file. It is safe for concurrent use.
+type Cache struct {
+	path string
+
+	mu      sync.Mutex
+	entries map[string]Entry
+	dirty   bool
+}
+
+type file struct {
+	Version int
+	Entries map[string]Entry
+}
+
+// DefaultPath returns the cache file location under the XDG cache dir
+func DefaultPath() string {
+	base := xdg.CacheHome()
+	if base == "" {
+		return ""
+	}
+	return filepath.Join(base, "agc", "dirsizes.gob")
+}
+
+// Open loads the cache at path. A missing, unreadable or outdated file
+// yields an empty cache rather than an error, since the cache can always be
+// rebuilt.
+func Open(path string) *Cache {
+	c := &Cache{path: path, entries: make(map[string]Entry)}
+	if path == "" {
+		return c
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return c
+	}
+	defer f.Close()
+
+	var data file
+	if err := gob.NewDecoder(f).Decode(&data); err != nil || data.Version != version {
+		return c
+	}
+	if data.Entries != nil {
+		c.entries = data.Entries
+	}
+	return c
+}
+
+// Lookup returns the entry for dir if its signature still matches
+func (c *Cache) Lookup(dir string, sig Signature) (Entry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[dir]
+	if !ok || e.Sig != sig {
+		return Entry{}, false
+	}
+	e.Used = time.Now().Unix()
+	c.entries[dir] = e
+	c.dirty = true
+	return e, true
+}
+
+// Store records the listing of dir
+func (c *Cache) Store(dir string, e Entry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e.Used = time.Now().Unix()
+	c.entries[dir] = e
+	c.dirty = true
+}
+
+// Forget drops the entries for path, everything below it, and its parent
+// directory, whose listing changes when path is removed
+func (c *Cache) Forget(path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	path = filepath.Clean(path)
+	prefix := path + string(filepath.Separator)
+	for dir := range c.entries {
+		if dir == path || strings.HasPrefix(dir, prefix) {
+			delete(c.entries, dir)
+			c.dirty = true
+		}
+	}
+	if _, ok := c.entries[filepath.Dir(path)]; ok {
+		delete(c.entries, filepath.Dir(path))
+		c.dirty = true
+	}
+}
+
+// Len returns the number of cached directories
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// Save writes the cache back to disk if it changed, dropping entries that
+// haven't been used for a while
+func (c *Cache) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.dirty || c.path == "" {
+		return nil
+	}
+
+	cutoff := time.Now().Add(-maxIdle).Unix()
+	for dir, e := range c.entries {
+		if e.Used < cutoff {
+			delete(c.entries, dir)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), ".dirsizes-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := gob.NewEncoder(tmp).Encode(file{Version: version, Entries: c.entries}); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), c.path); err != nil {
+		return err
+	}
+	c.dirty = false
+	return nil
+}
+
+// Invalidate forgets the given paths in the cache file at path. It is called
+// after cleaning so the next scan can't reuse listings of removed trees.
+func Invalidate(path string, paths []string) error {
+	if path == "" || len(paths) == 0 {
+		return nil
+	}
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	c := Open(path)
+	for _, p := range paths {
+		c.Forget(p)
+	}
+	return c.Save()
+}
+
+// Clear deletes the cache file at path
+func Clear(path string) error {
+	if path == "" {
+		return nil
+	}
+	err := os.Remove(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,175 @@
+package sizecache
+
+import (
+	"encoding/gob"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLookup(t *testing.T) {
+	c := Open("")
+	sig := Signature{ModTime: 100, Dev: 1, Ino: 2}
+	c.Store("/a", Entry{Sig: sig, Apparent: 10})
+
+	if e, ok := c.Lookup("/a", sig); !ok || e.Apparent != 10 {
+		t.Errorf("Lookup with the stored signature = %+v, %v", e, ok)
+	}
+	for _, other := range []Signature{{ModTime: 101, Dev: 1, Ino: 2}, {ModTime: 100, Dev: 9, Ino: 2}, {ModTime: 100, Dev: 1, Ino: 9}} {
+		if _, ok := c.Lookup("/a", other); ok {
+			t.Errorf("Lookup matched changed signature %+v", other)
+		}
+	}
+	if _, ok := c.Lookup("/b", sig); ok {
+		t.Error("Lookup found a directory never stored")
+	}
+}
+
+func TestSaveOpen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agc", "dirsizes.gob")
+	c := Open(path)
+	sig := Signature{ModTime: 100}
+	c.Store("/a", Entry{Sig: sig, Apparent: 10, Subdirs: []string{"b"}, Links: []Link{{Dev: 1, Ino: 2, Disk: 4096}}})
+	if err := c.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened := Open(path)
+	e, ok := reopened.Lookup("/a", sig)
+	if !ok || e.Apparent != 10 || len(e.Subdirs) != 1 || len(e.Links) != 1 || e.Links[0].Disk != 4096 {
+		t.Errorf("reopened entry %+v, %v", e, ok)
+	}
+}
+
+func TestOpenFallback(t *testing.T) {
+	dir := t.TempDir()
+
+	garbage := filepath.Join(dir, "garbage.gob")
+	if err := os.WriteFile(garbage, []byte("not a gob file"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if c := Open(garbage); c.Len() != 0 {
+		t.Errorf("corrupt file gave %d entries", c.Len())
+	}
+
+	// A file written by another version is ignored
+	old := filepath.Join(dir, "old.gob")
+	f, err := os.Create(old)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = gob.NewEncoder(f).Encode(file{Version: version - 1, Entries: map[string]Entry{"/a": {}}})
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := Open(old); c.Len() != 0 {
+		t.Errorf("outdated file gave %d entries", c.Len())
+	}
+
+	if c := Open(filepath.Join(dir, "missing.gob")); c.Len() != 0 {
+		t.Errorf("missing file gave %d entries", c.Len())
+	}
+}
+
+func TestForget(t *testing.T) {
+	c := Open("")
+	for _, dir := range []string{"/p", "/p/build", "/p/build/sub", "/p/build2", "/p/lib", "/q"} {
+		c.Store(filepath.FromSlash(dir), Entry{})
+	}
+	c.Forget(filepath.FromSlash("/p/build"))
+
+	// The parent's listing changes when build is removed; siblings and
+	// names sharing the prefix stay
+	if got := keys(c); got != "/p/build2 /p/lib /q" {
+		t.Errorf("left %s", got)
+	}
+}
+
+func TestInvalidate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dirsizes.gob")
+	if err := Invalidate(path, []string{"/p"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Invalidate created a cache file: %v", err)
+	}
+
+	c := Open(path)
+	for _, dir := range []string{"/p", "/p/a/build", "/p/a", "/p/b"} {
+		c.Store(filepath.FromSlash(dir), Entry{})
+	}
+	if err := c.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if err := Invalidate(path, []string{filepath.FromSlash("/p/a/build")}); err != nil {
+		t.Fatal(err)
+	}
+	if got := keys(Open(path)); got != "/p /p/b" {
+		t.Errorf("left %s", got)
+	}
+}
+
+func TestSavePrunesIdle(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dirsizes.gob")
+	c := Open(path)
+	c.Store("/fresh", Entry{})
+	c.Store("/stale", Entry{})
+	c.entries["/stale"] = Entry{Used: time.Now().Add(-maxIdle - time.Hour).Unix()}
+	if err := c.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if got := keys(Open(path)); got != "/fresh" {
+		t.Errorf("left %s", got)
+	}
+}
+
+func TestRacy(t *testing.T) {
+	start := time.Now()
+	tests := []struct {
+		mod  time.Time
+		want bool
+	}{
+		{start.Add(-time.Hour), false},
+		{start.Add(-racyWindow - time.Millisecond), false},
+		{start.Add(-time.Second), true},
+		{start, true},
+		{start.Add(time.Minute), true},
+	}
+	for _, tt := range tests {
+		if got := (Signature{ModTime: tt.mod.UnixNano()}).Racy(start); got != tt.want {
+			t.Errorf("mtime %v before start: racy %v, want %v", start.Sub(tt.mod), got, tt.want)
+		}
+	}
+}
+
+func TestClear(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dirsizes.gob")
+	if err := Clear(path); err != nil {
+		t.Errorf("clearing a missing cache: %v", err)
+	}
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Clear(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("cache file still there: %v", err)
+	}
+}
+
+// keys lists the cached directories in order, with forward slashes
+func keys(c *Cache) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var dirs []string
+	for dir := range c.entries {
+		dirs = append(dirs, filepath.ToSlash(dir))
+	}
+	sort.Strings(dirs)
+	return strings.Join(dirs, " ")
+}
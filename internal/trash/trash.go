@@ -0,0 +1,12 @@
+// Package trash moves files to the platform's trash instead of deleting
+// them, so a cleanup can be undone until the trash is emptied. The trash
+// always lives on the same filesystem as the item, so trashing frees no
+// space by itself.
+package trash
+
+// Move puts path in the trash and returns where it now is. mount is the
+// mount point of the filesystem holding path; it is used when that
+// filesystem needs its own trash directory and may be empty otherwise.
+func Move(path, mount string) (string, error) {
+	return move(path, mount)
+}
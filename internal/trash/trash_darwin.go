@@ -0,0 +1,53 @@
+package trash
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// move renames path into ~/.Trash, or into $mount/.Trashes/$uid for items
+// on other volumes, the directories Finder uses
+func move(path, mount string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", errors.New("no home directory for the trash")
+	}
+	dir := filepath.Join(home, ".Trash")
+	if !sameDevice(path, home) {
+		if mount == "" {
+			return "", fmt.Errorf("%s is on another volume than the home trash", path)
+		}
+		dir = filepath.Join(mount, ".Trashes", strconv.Itoa(os.Getuid()))
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", fmt.Errorf("no trash for %s: %w", path, err)
+	}
+
+	// Finder names duplicates "name 2", "name 3", ... before the extension
+	ext := filepath.Ext(path)
+	stem := strings.TrimSuffix(filepath.Base(path), ext)
+	for i := 1; ; i++ {
+		name := stem + ext
+		if i > 1 {
+			name = fmt.Sprintf("%s %d%s", stem, i, ext)
+		}
+		target := filepath.Join(dir, name)
+		if _, err := os.Lstat(target); err == nil {
+			continue
+		}
+		if err := os.Rename(path, target); err != nil {
+			return "", err
+		}
+		return target, nil
+	}
+}
+
+func sameDevice(a, b string) bool {
+	var sa, sb syscall.Stat_t
+	return syscall.Lstat(a, &sa) == nil && syscall.Stat(b, &sb) == nil && sa.Dev == sb.Dev
+}
@@ -0,0 +1,140 @@
+//go:build !windows && !darwin
+
+package trash
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/iml1s/antigravity-cleaner/internal/xdg"
+)
+
+// move follows the FreeDesktop.org Trash specification: items on the
+// home filesystem go to $XDG_DATA_HOME/Trash, and items on other
+// filesystems to $topdir/.Trash/$uid or $topdir/.Trash-$uid
+func move(path, mount string) (string, error) {
+	dataHome := xdg.DataHome()
+	if dataHome == "" {
+		return "", errors.New("no home directory for the trash")
+	}
+	dir, topdir := filepath.Join(dataHome, "Trash"), ""
+	if !sameDevice(path, dir) {
+		if mount == "" {
+			return "", fmt.Errorf("%s is on another filesystem than the home trash", path)
+		}
+		var err error
+		if dir, err = topdirTrash(mount); err != nil {
+			return "", err
+		}
+		topdir = mount
+	}
+	return trashInto(dir, topdir, path)
+}
+
+// sameDevice reports whether path is on the filesystem that holds dir, or
+// would hold it once created
+func sameDevice(path, dir string) bool {
+	var a, b syscall.Stat_t
+	if syscall.Lstat(path, &a) != nil {
+		return false
+	}
+	for {
+		if syscall.Stat(dir, &b) == nil {
+			return a.Dev == b.Dev
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return false
+		}
+		dir = parent
+	}
+}
+
+// topdirTrash returns the trash directory of the filesystem mounted at
+// topdir: the shared .Trash/$uid when an administrator set up .Trash as a
+// sticky directory, and otherwise a private .Trash-$uid
+func topdirTrash(topdir string) (string, error) {
+	uid := strconv.Itoa(os.Getuid())
+	shared := filepath.Join(topdir, ".Trash")
+	if info, err := os.Lstat(shared); err == nil && info.IsDir() && info.Mode()&fs.ModeSticky != 0 {
+		dir := filepath.Join(shared, uid)
+		if err := os.MkdirAll(dir, 0o700); err == nil {
+			return dir, nil
+		}
+	}
+
+	dir := filepath.Join(topdir, ".Trash-"+uid)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", fmt.Errorf("no trash on %s: %w", topdir, err)
+	}
+	// A symlink planted in place of the directory could redirect items
+	// elsewhere
+	if info, err := os.Lstat(dir); err != nil || !info.IsDir() {
+		return "", fmt.Errorf("no trash on %s: %s is not a directory", topdir, dir)
+	}
+	return dir, nil
+}
+
+// trashInto moves path into the files directory of the trash at dir,
+// recording its original location in a .trashinfo file first, as the spec
+// requires. Paths in a topdir trash are recorded relative to topdir.
+func trashInto(dir, topdir, path string) (string, error) {
+	filesDir, infoDir := filepath.Join(dir, "files"), filepath.Join(dir, "info")
+	for _, d := range []string{filesDir, infoDir} {
+		if err := os.MkdirAll(d, 0o700); err != nil {
+			return "", err
+		}
+	}
+
+	recorded := path
+	if topdir != "" {
+		if rel, err := filepath.Rel(topdir, path); err == nil {
+			recorded = rel
+		}
+	}
+	info := fmt.Sprintf("[Trash Info]\nPath=%s\nDeletionDate=%s\n",
+		(&url.URL{Path: recorded}).EscapedPath(), time.Now().Format("2006-01-02T15:04:05"))
+
+	base := filepath.Base(path)
+	for i := 1; ; i++ {
+		name := base
+		if i > 1 {
+			name = fmt.Sprintf("%s.%d", base, i)
+		}
+		// Creating the info file exclusively reserves the name
+		infoPath := filepath.Join(infoDir, name+".trashinfo")
+		f, err := os.OpenFile(infoPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+		if errors.Is(err, fs.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		target := filepath.Join(filesDir, name)
+		if _, err := os.Lstat(target); err == nil {
+			// Left behind without its info file; don't overwrite it
+			f.Close()
+			os.Remove(infoPath)
+			continue
+		}
+		_, err = f.WriteString(info)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err == nil {
+			err = os.Rename(path, target)
+		}
+		if err != nil {
+			os.Remove(infoPath)
+			return "", err
+		}
+		return target, nil
+	}
+}
@@ -0,0 +1,53 @@
+package trash
+
+import (
+	"errors"
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var procSHFileOperationW = windows.NewLazySystemDLL("shell32.dll").NewProc("SHFileOperationW")
+
+// shFileOpStruct is SHFILEOPSTRUCTW
+type shFileOpStruct struct {
+	hwnd                  uintptr
+	wFunc                 uint32
+	pFrom                 *uint16
+	pTo                   *uint16
+	fFlags                uint16
+	fAnyOperationsAborted int32
+	hNameMappings         uintptr
+	lpszProgressTitle     *uint16
+}
+
+const (
+	foDelete          = 0x3
+	fofSilent         = 0x4
+	fofNoConfirmation = 0x10
+	fofAllowUndo      = 0x40
+	fofNoErrorUI      = 0x400
+)
+
+// move sends path to the Recycle Bin of its volume
+func move(path, mount string) (string, error) {
+	from, err := windows.UTF16FromString(path)
+	if err != nil {
+		return "", err
+	}
+	// pFrom is a list of paths ending with an extra NUL
+	from = append(from, 0)
+	op := shFileOpStruct{
+		wFunc:  foDelete,
+		pFrom:  &from[0],
+		fFlags: fofAllowUndo | fofNoConfirmation | fofSilent | fofNoErrorUI,
+	}
+	if r, _, _ := procSHFileOperationW.Call(uintptr(unsafe.Pointer(&op))); r != 0 {
+		return "", fmt.Errorf("moving %s to the Recycle Bin failed with code %#x", path, r)
+	}
+	if op.fAnyOperationsAborted != 0 {
+		return "", errors.New("moving to the Recycle Bin was cancelled")
+	}
+	return "Recycle Bin", nil
+}
@@ -0,0 +1,139 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/iml1s/antigravity-cleaner/internal/scanner"
+	"golang.org/x/term"
+)
+
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// Progress renders scan events as a single, continuously updated status
+// line. Handle may be called from any goroutine.
+type Progress struct {
+	out   io.Writer
+	width int
+
+	mu       sync.Mutex
+	running  map[string]bool
+	dirs     int64
+	bytes    int64
+	items    int
+	lastPath string
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewProgress starts a live progress line on stderr. It returns nil when
+// stderr isn't a terminal, so callers can skip progress reporting for
+// redirected output.
+func NewProgress() *Progress {
+	fd := int(os.Stderr.Fd())
+	if !term.IsTerminal(fd) {
+		return nil
+	}
+	width, _, err := term.GetSize(fd)
+	if err != nil || width <= 0 {
+		width = 80
+	}
+
+	p := &Progress{
+		out:     os.Stderr,
+		width:   width,
+		running: make(map[string]bool),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go p.loop()
+	return p
+}
+
+// Handle records a scan event for the next redraw
+func (p *Progress) Handle(ev scanner.Event) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	switch ev.Kind {
+	case scanner.DirEntered:
+		p.dirs++
+		p.lastPath = ev.Path
+		p.running[ev.Provider] = true
+	case scanner.BytesCounted:
+		if ev.Bytes > p.bytes {
+			p.bytes = ev.Bytes
+		}
+	case scanner.ItemFound:
+		p.items++
+	case scanner.ProviderDone:
+		delete(p.running, ev.Provider)
+	}
+}
+
+// Stop erases the progress line and stops redrawing
+func (p *Progress) Stop() {
+	close(p.stop)
+	<-p.done
+	fmt.Fprint(p.out, "\r\033[K")
+}
+
+func (p *Progress) loop() {
+	defer close(p.done)
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	for frame := 0; ; frame++ {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			fmt.Fprint(p.out, "\r\033[K"+p.line(spinnerFrames[frame%len(spinnerFrames)]))
+		}
+	}
+}
+
+// line formats the status line, shortening the current path to fit
+func (p *Progress) line(spinner string) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var providers []string
+	for id := range p.running {
+		if id != "" {
+			providers = append(providers, id)
+		}
+	}
+	sort.Strings(providers)
+	scanning := "Scanning"
+	if len(providers) > 0 {
+		scanning += " " + strings.Join(providers, ", ")
+	}
+
+	status := fmt.Sprintf("%s %s · %s dirs · %s counted · %d items",
+		spinner, scanning, humanize.Comma(p.dirs), humanize.Bytes(uint64(p.bytes)), p.items)
+
+	room := p.width - len([]rune(status)) - 4
+	if room > 10 && p.lastPath != "" {
+		status += " · " + truncateLeft(p.lastPath, room)
+	} else if r := []rune(status); len(r) > p.width-1 {
+		status = string(r[:p.width-1])
+	}
+	return helpStyle.Render(status)
+}
+
+// truncateLeft keeps the end of s, which is the informative part of a path
+func truncateLeft(s string, max int) string {
+	r := []rune(s)
+	if len(r) <= max {
+		return s
+	}
+	return "…" + string(r[len(r)-max+1:])
+}
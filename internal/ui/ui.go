@@ -1,15 +1,23 @@
 package ui
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/dustin/go-humanize"
+	"github.com/iml1s/antigravity-cleaner/internal/cleaner"
+	"github.com/iml1s/antigravity-cleaner/internal/fsinfo"
+	"github.com/iml1s/antigravity-cleaner/internal/rules"
 	"github.com/iml1s/antigravity-cleaner/internal/scanner"
+	"github.com/iml1s/antigravity-cleaner/internal/units"
 )
 
 var (
@@ -35,35 +43,58 @@ var (
 			Foreground(lipgloss.Color("241"))
 )
 
-// DisplayScanResults shows scan results in a formatted table
-func DisplayScanResults(items []scanner.CleanableItem) {
+// DisplayScanResults shows scan results in a formatted table. verbose lists
+// every path that couldn't be read instead of just counting them.
+func DisplayScanResults(result scanner.Result, verbose bool) {
+	items := result.Items
 	if len(items) == 0 {
-		fmt.Println("✨ No cleanable items found!")
+		if result.Incomplete() {
+			fmt.Println(incompleteNotice(result.Err))
+		} else {
+			fmt.Println("✨ No cleanable items found!")
+		}
+		if result.HiddenItems > 0 {
+			fmt.Println(helpStyle.Render(hiddenNotice(result)))
+		}
+		if result.RecentItems > 0 {
+			fmt.Println(helpStyle.Render(recentNotice(result)))
+		}
+		displayExclusions(result, verbose)
+		displayScanErrors(result, verbose)
 		return
 	}
 
-	// Sort by size descending
-	sort.Slice(items, func(i, j int) bool {
-		return items[i].Size > items[j].Size
+	// Sort by size descending; stable so equal sizes keep scan order
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].DiskSize > items[j].DiskSize
 	})
 
 	// Group by category
 	categories := make(map[string][]scanner.CleanableItem)
+	catSizes := make(map[string]int64)
+	var order []string
 	var totalSize int64
 
 	for _, item := range items {
+		if _, ok := categories[item.Category]; !ok {
+			order = append(order, item.Category)
+		}
 		categories[item.Category] = append(categories[item.Category], item)
-		totalSize += item.Size
+		catSizes[item.Category] += item.DiskSize
+		totalSize += item.DiskSize
 	}
 
+	// Largest category first
+	sort.SliceStable(order, func(i, j int) bool {
+		return catSizes[order[i]] > catSizes[order[j]]
+	})
+
 	fmt.Println(titleStyle.Render("🔍 Scan Results"))
 	fmt.Println()
 
-	for category, catItems := range categories {
-		var catSize int64
-		for _, item := range catItems {
-			catSize += item.Size
-		}
+	for _, category := range order {
+		catItems := categories[category]
+		catSize := catSizes[category]
 
 		fmt.Printf("📁 %s (%s)\n", category, humanize.Bytes(uint64(catSize)))
 
@@ -82,21 +113,191 @@ func DisplayScanResults(items []scanner.CleanableItem) {
 				levelIcon = "⛔"
 			}
 
-			fmt.Printf("   %s %s %s\n",
+			size := sizeLabel(item)
+			if len(item.Errors) > 0 {
+				size += cautionStyle.Render(fmt.Sprintf(" ⚠ %d unreadable", len(item.Errors)))
+			}
+			fmt.Printf("   %s %s %s %s\n",
 				levelStyle.Render(levelIcon),
 				levelStyle.Render(fmt.Sprintf("%-40s", item.Description)),
-				humanize.Bytes(uint64(item.Size)))
+				ageLabel(item),
+				size)
+			if verbose {
+				for _, err := range item.Errors {
+					fmt.Println(helpStyle.Render(fmt.Sprintf("       %s: %v", err.Path, err.Err)))
+				}
+			}
 		}
 		fmt.Println()
 	}
 
 	fmt.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
 	fmt.Printf("📊 Total cleanable: %s\n", humanize.Bytes(uint64(totalSize)))
+	if result.HiddenItems > 0 {
+		fmt.Println(helpStyle.Render(hiddenNotice(result)))
+	}
+	if result.RecentItems > 0 {
+		fmt.Println(helpStyle.Render(recentNotice(result)))
+	}
+	if result.Incomplete() {
+		fmt.Println(incompleteNotice(result.Err))
+	}
+	displayFilesystems(items)
+	displayExclusions(result, verbose)
+	displayScanErrors(result, verbose)
 	fmt.Println()
 	fmt.Println(helpStyle.Render("Legend: ✓ Safe  ⚠ Caution  ⛔ Warning"))
 	fmt.Println(helpStyle.Render("Run 'agc clean' to interactively select items to clean"))
 }
 
+// displayExclusions counts the paths left out by exclude patterns and
+// .agcignore files, listing them with the matching pattern in verbose mode
+func displayExclusions(result scanner.Result, verbose bool) {
+	count := len(result.Excluded)
+	if count == 0 {
+		return
+	}
+
+	noun := "paths"
+	if count == 1 {
+		noun = "path"
+	}
+	msg := fmt.Sprintf("🛡️  %d %s excluded by config or .agcignore", count, noun)
+	if !verbose {
+		msg += " (use --verbose to list them)"
+	}
+	fmt.Println(helpStyle.Render(msg))
+
+	if verbose {
+		for _, ex := range result.Excluded {
+			fmt.Println(helpStyle.Render(fmt.Sprintf("   %s (%s)", ex.Path, ex.Reason)))
+		}
+	}
+}
+
+// displayScanErrors summarizes unreadable paths. Item errors are already
+// listed under their items in verbose mode, so only provider errors are
+// spelled out here.
+func displayScanErrors(result scanner.Result, verbose bool) {
+	count := result.ErrorCount()
+	if count == 0 {
+		return
+	}
+
+	noun := "paths"
+	if count == 1 {
+		noun = "path"
+	}
+	msg := fmt.Sprintf("⚠️  %d %s could not be read; some sizes or items may be missing", count, noun)
+	if !verbose {
+		msg += " (use --verbose for details)"
+	}
+	fmt.Println(cautionStyle.Render(msg))
+
+	if verbose {
+		for _, err := range result.Errors {
+			fmt.Println(helpStyle.Render(fmt.Sprintf("   [%s] %s: %v", err.Provider, err.Path, err.Err)))
+		}
+	}
+}
+
+// sizeLabel formats an item's reclaimable size, adding the apparent size
+// when sparse files or hardlinks make the two differ noticeably
+func sizeLabel(item scanner.CleanableItem) string {
+	label := humanize.Bytes(uint64(item.DiskSize))
+	if item.Incomplete {
+		label = "≥ " + label
+	}
+	if item.Size > item.DiskSize+item.DiskSize/10 {
+		label += helpStyle.Render(fmt.Sprintf(" (apparent %s)", humanize.Bytes(uint64(item.Size))))
+	}
+	return label
+}
+
+// hiddenNotice reports how much the size thresholds left out
+func hiddenNotice(result scanner.Result) string {
+	noun := "items"
+	if result.HiddenItems == 1 {
+		noun = "item"
+	}
+	return fmt.Sprintf("🙈 %d smaller %s (%s) hidden by size thresholds; use --min-size 0 to show them",
+		result.HiddenItems, noun, humanize.Bytes(uint64(result.HiddenBytes)))
+}
+
+// recentNotice reports how much --older-than left out
+func recentNotice(result scanner.Result) string {
+	noun := "items"
+	if result.RecentItems == 1 {
+		noun = "item"
+	}
+	return fmt.Sprintf("🕒 %d recently used %s (%s) skipped by --older-than",
+		result.RecentItems, noun, humanize.Bytes(uint64(result.RecentBytes)))
+}
+
+// ageLabel formats how long ago an item was last used as a fixed-width
+// column, blank when unknown
+func ageLabel(item scanner.CleanableItem) string {
+	age := ""
+	if !item.LastUsed().IsZero() {
+		age = units.FormatAge(item.Age(time.Now()))
+	}
+	return helpStyle.Render(fmt.Sprintf("%5s", age))
+}
+
+// displayFilesystems shows the free space of each filesystem holding
+// items, now and once the items are cleaned
+func displayFilesystems(items []scanner.CleanableItem) {
+	usages := cleaner.Filesystems(items)
+	if len(usages) == 0 {
+		return
+	}
+	fmt.Println("💽 Free space:")
+	for _, u := range usages {
+		if u.Err != nil {
+			fmt.Printf("   %-20s %s cleanable; free space unknown: %v\n", filesystemLabel(u.Filesystem), humanize.Bytes(uint64(u.Bytes)), u.Err)
+			continue
+		}
+		fmt.Printf("   %-20s %s free of %s (%.1f%%) → %s (%.1f%%) after cleaning %s\n",
+			filesystemLabel(u.Filesystem),
+			humanize.Bytes(uint64(u.Free)), humanize.Bytes(uint64(u.Total)), u.FreePercent(0),
+			humanize.Bytes(uint64(u.FreeAfter())), u.FreePercent(u.Bytes),
+			humanize.Bytes(uint64(u.Bytes)))
+	}
+}
+
+// displayFreeNow shows the free space left on each filesystem items were
+// removed from
+func displayFreeNow(report cleaner.CleanReport) {
+	var removed []scanner.CleanableItem
+	for _, result := range report.Items {
+		if result.Outcome == cleaner.Removed {
+			removed = append(removed, result.Item)
+		}
+	}
+	for _, u := range cleaner.Filesystems(removed) {
+		if u.Err == nil {
+			fmt.Printf("💽 %s now has %s free (%.1f%%)\n", filesystemLabel(u.Filesystem), humanize.Bytes(uint64(u.Free)), u.FreePercent(0))
+		}
+	}
+}
+
+// filesystemLabel names a filesystem by its mount point and type
+func filesystemLabel(fs fsinfo.Filesystem) string {
+	if fs.Type == "" {
+		return fs.Mount
+	}
+	return fmt.Sprintf("%s (%s)", fs.Mount, fs.Type)
+}
+
+// incompleteNotice explains why a scan's numbers are lower bounds
+func incompleteNotice(err error) string {
+	reason := "was interrupted"
+	if errors.Is(err, context.DeadlineExceeded) {
+		reason = "timed out"
+	}
+	return cautionStyle.Render(fmt.Sprintf("⚠️  Scan %s; results are incomplete (sizes marked ≥ are lower bounds)", reason))
+}
+
 // SelectItems allows interactive selection of items to clean
 func SelectItems(items []scanner.CleanableItem) []scanner.CleanableItem {
 	if len(items) == 0 {
@@ -104,8 +305,8 @@ func SelectItems(items []scanner.CleanableItem) []scanner.CleanableItem {
 	}
 
 	// Sort by size descending
-	sort.Slice(items, func(i, j int) bool {
-		return items[i].Size > items[j].Size
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].DiskSize > items[j].DiskSize
 	})
 
 	m := initialModel(items)
@@ -138,14 +339,211 @@ func DisplayDryRun(items []scanner.CleanableItem) {
 	fmt.Println()
 
 	for _, item := range items {
-		fmt.Printf("  • %s (%s)\n", item.Description, humanize.Bytes(uint64(item.Size)))
+		fmt.Printf("  • %s (%s)\n", item.Description, sizeLabel(item))
 		fmt.Printf("    %s\n", item.Path)
-		totalSize += item.Size
+		totalSize += item.DiskSize
 	}
 
 	fmt.Println()
 	fmt.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
 	fmt.Printf("📊 Would free: %s\n", humanize.Bytes(uint64(totalSize)))
+	displayFilesystems(items)
+}
+
+// DisplayFreePlan shows the items chosen to reach a space target, with a
+// running total, and warns when the plan needs risky items or falls short
+func DisplayFreePlan(plan cleaner.FreePlan) {
+	fmt.Println(titleStyle.Render(fmt.Sprintf("🎯 Plan to free %s", humanize.Bytes(uint64(plan.Target)))))
+	fmt.Println()
+
+	var running int64
+	for _, item := range plan.Items {
+		running += item.DiskSize
+		levelStyle := levelStyleFor(item.SafeLevel)
+		fmt.Printf("  %s %s %s %s %s\n",
+			levelStyle.Render(levelIconFor(item.SafeLevel)),
+			levelStyle.Render(fmt.Sprintf("%-40s", item.Description)),
+			ageLabel(item),
+			fmt.Sprintf("%10s", humanize.Bytes(uint64(item.DiskSize))),
+			helpStyle.Render(fmt.Sprintf("(total %s)", humanize.Bytes(uint64(running)))))
+	}
+
+	fmt.Println()
+	fmt.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
+	fmt.Printf("📊 Plan deletes %s, %s of the %s target\n",
+		Pluralize(len(plan.Items), "item"), humanize.Bytes(uint64(plan.Bytes)), humanize.Bytes(uint64(plan.Target)))
+	displayFilesystems(plan.Items)
+
+	switch {
+	case !plan.Met():
+		fmt.Println(warningStyle.Render(fmt.Sprintf("⚠️  Target can't be met: everything found adds up to %s, %s short",
+			humanize.Bytes(uint64(plan.Available)), humanize.Bytes(uint64(plan.Target-plan.Bytes)))))
+		if plan.HeldBack > 0 {
+			fmt.Println(warningStyle.Render(fmt.Sprintf("   %s more available above %s level; add %s to plan with them",
+				humanize.Bytes(uint64(plan.HeldBack)), plan.HeldAbove, IncludeHint(plan.HeldAbove))))
+		}
+	case plan.MaxSafety == "caution" || plan.MaxSafety == "warning":
+		fmt.Println(levelStyleFor(plan.MaxSafety).Render(fmt.Sprintf("⚠️  Safe items aren't enough; the plan includes %s items", plan.MaxSafety)))
+	}
+}
+
+// Confirm asks a yes/no question on the terminal, defaulting to no
+func Confirm(question string) bool {
+	fmt.Printf("%s [y/N] ", question)
+	var answer string
+	fmt.Scanln(&answer)
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// ConfirmRisky lists the warning-level items among items and asks for the
+// number of them, or "yes", to be typed before cleaning. It returns true
+// without asking when there are none.
+func ConfirmRisky(items []scanner.CleanableItem) bool {
+	var risky []scanner.CleanableItem
+	for _, item := range items {
+		if item.SafeLevel == "warning" {
+			risky = append(risky, item)
+		}
+	}
+	if len(risky) == 0 {
+		return true
+	}
+
+	noun := "items"
+	if len(risky) == 1 {
+		noun = "item"
+	}
+	fmt.Println()
+	fmt.Println(warningStyle.Render(fmt.Sprintf("⛔ %d warning-level %s selected; they may hold data that can't be recreated:", len(risky), noun)))
+	for _, item := range risky {
+		fmt.Printf("   %s (%s) %s\n", item.Description, humanize.Bytes(uint64(item.DiskSize)), helpStyle.Render(item.Path))
+	}
+	fmt.Printf("Type %d or yes to clean them: ", len(risky))
+	var answer string
+	fmt.Scanln(&answer)
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "yes" || answer == strconv.Itoa(len(risky))
+}
+
+// ConfirmInUse lists the items running processes are using and asks
+// whether to clean them anyway. It returns true without asking when there
+// are none; declined items are skipped by the cleaner.
+func ConfirmInUse(found []cleaner.InUse) bool {
+	if len(found) == 0 {
+		return true
+	}
+
+	noun := "items are"
+	if len(found) == 1 {
+		noun = "item is"
+	}
+	fmt.Println()
+	fmt.Println(cautionStyle.Render(fmt.Sprintf("⚠ %d %s in use; cleaning while the application runs can corrupt its state:", len(found), noun)))
+	for _, f := range found {
+		names := make([]string, len(f.Processes))
+		for i, p := range f.Processes {
+			names[i] = p.String()
+		}
+		fmt.Printf("   %s: %s %s\n", f.Item.Description, strings.Join(names, ", "), helpStyle.Render(f.Item.Path))
+	}
+	return Confirm("Clean them anyway? Otherwise they are skipped.")
+}
+
+// DisplayCleanResult prints the outcome of one item as soon as it's cleaned
+func DisplayCleanResult(result cleaner.ItemResult) {
+	if result.Outcome == cleaner.Skipped {
+		fmt.Printf("  %s %s: skipped, %s\n", helpStyle.Render("–"), result.Item.Description, result.Reason)
+		return
+	}
+	if result.Err != nil {
+		fmt.Printf("  %s %s: %v\n", warningStyle.Render("❌"), result.Item.Description, result.Err)
+		return
+	}
+	if result.Outcome == cleaner.Quarantined {
+		fmt.Printf("  %s %s: %s quarantined as %s\n", safeStyle.Render("📦"), result.Item.Description, humanize.Bytes(uint64(result.Item.DiskSize)), result.QuarantineID)
+		return
+	}
+	if result.Outcome == cleaner.Trashed {
+		fmt.Printf("  %s %s: %s moved to the trash\n", safeStyle.Render("🗑"), result.Item.Description, humanize.Bytes(uint64(result.Item.DiskSize)))
+		return
+	}
+	fmt.Printf("  %s %s: %s freed\n", safeStyle.Render("✓"), result.Item.Description, humanize.Bytes(uint64(result.BytesFreed)))
+}
+
+// DisplayCleanReport prints the totals of a clean run and lists the failures
+func DisplayCleanReport(report cleaner.CleanReport) {
+	if report.CacheErr != nil {
+		fmt.Printf("⚠️  Could not update size cache: %v\n", report.CacheErr)
+	}
+
+	fmt.Println()
+	removed, quarantined, trashed := report.Count(cleaner.Removed), report.Count(cleaner.Quarantined), report.Count(cleaner.Trashed)
+	var done []string
+	if removed > 0 || quarantined+trashed == 0 {
+		done = append(done, fmt.Sprintf("cleaned %s, freed %s", Pluralize(removed, "item"), humanize.Bytes(uint64(report.BytesFreed))))
+	}
+	if quarantined > 0 {
+		done = append(done, fmt.Sprintf("quarantined %s (%s)", Pluralize(quarantined, "item"), humanize.Bytes(uint64(report.BytesQuarantined))))
+	}
+	if trashed > 0 {
+		done = append(done, fmt.Sprintf("moved %s (%s) to the trash", Pluralize(trashed, "item"), humanize.Bytes(uint64(report.BytesTrashed))))
+	}
+	summary := strings.Join(done, ", ")
+	fmt.Printf("✨ Done! %s%s in %s\n", strings.ToUpper(summary[:1]), summary[1:], report.Duration.Round(time.Millisecond))
+	if quarantined > 0 {
+		fmt.Println(helpStyle.Render(fmt.Sprintf("📦 %s stays on disk until 'agc quarantine purge'; 'agc restore <id>' puts items back",
+			humanize.Bytes(uint64(report.BytesQuarantined)))))
+	}
+	if trashed > 0 {
+		fmt.Println(cautionStyle.Render(fmt.Sprintf("⚠️  The trash is on the same filesystem, so %s is only freed once it's emptied; use --strategy delete to delete right away",
+			humanize.Bytes(uint64(report.BytesTrashed)))))
+	}
+	if skipped := report.Count(cleaner.Skipped); skipped > 0 {
+		fmt.Println(helpStyle.Render(fmt.Sprintf("   %s skipped", Pluralize(skipped, "item"))))
+	}
+	inUse := 0
+	for _, result := range report.Items {
+		if len(result.InUse) > 0 {
+			inUse++
+		}
+	}
+	if inUse > 0 {
+		noun := "items were"
+		if inUse == 1 {
+			noun = "item was"
+		}
+		fmt.Println(cautionStyle.Render(fmt.Sprintf("⚠️  %d %s skipped because applications are using them; close them and run again, or use --force", inUse, noun)))
+	}
+	displayFreeNow(report)
+
+	failed := report.Count(cleaner.Failed)
+	if failed == 0 {
+		return
+	}
+	fmt.Println(warningStyle.Render(fmt.Sprintf("⚠️  %s failed to clean:", Pluralize(failed, "item"))))
+	for _, result := range report.Items {
+		if result.Outcome == cleaner.Failed {
+			fmt.Printf("   %s (%s): %v\n", result.Item.Description, result.Item.Path, result.Err)
+		}
+	}
+}
+
+// IncludeHint names the flags that let an unattended run clean items above
+// level
+func IncludeHint(level string) string {
+	if level == "caution" {
+		return "--include-warning"
+	}
+	return "--include-caution or --include-warning"
+}
+
+// Pluralize formats a count with a noun, adding an s unless n is 1
+func Pluralize(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
 }
 
 // Interactive selection model using bubbletea
@@ -235,7 +633,7 @@ func (m model) View() string {
 		checked := "[ ]"
 		if m.selected[i] {
 			checked = "[x]"
-			totalSelected += item.Size
+			totalSelected += item.DiskSize
 		}
 
 		var levelStyle lipgloss.Style
@@ -248,11 +646,12 @@ func (m model) View() string {
 			levelStyle = warningStyle
 		}
 
-		line := fmt.Sprintf("%s %s %s %s",
+		line := fmt.Sprintf("%s %s %s %s %s",
 			cursor,
 			checked,
 			levelStyle.Render(fmt.Sprintf("%-45s", item.Description)),
-			humanize.Bytes(uint64(item.Size)))
+			ageLabel(item),
+			sizeLabel(item))
 
 		if m.cursor == i {
 			line = selectedStyle.Render(line)
@@ -268,3 +667,74 @@ func (m model) View() string {
 
 	return s.String()
 }
+
+// DisplayRules lists rules with the paths they expand to on goos. An empty
+// goos shows the raw templates for every platform instead.
+func DisplayRules(rs []rules.Rule, goos string) {
+	fmt.Println(titleStyle.Render("📜 Rules"))
+
+	for _, category := range rules.Categories(rs) {
+		fmt.Printf("📁 %s\n", category)
+		for _, r := range rs {
+			if r.Category != category {
+				continue
+			}
+
+			minSize := "-"
+			if r.MinSize != "" {
+				minSize = r.MinSize
+			}
+			fmt.Printf("   %s %-36s %-8s min %-8s %s\n",
+				levelStyleFor(r.Safety).Render(levelIconFor(r.Safety)),
+				r.ID, r.Safety, minSize, helpStyle.Render(r.Source))
+			if len(r.Processes) > 0 {
+				fmt.Printf("       %s\n", helpStyle.Render("used by "+strings.Join(r.Processes, ", ")))
+			}
+
+			if goos == "" {
+				for _, platform := range []string{"all", "darwin", "linux", "windows"} {
+					for _, tmpl := range r.Paths[platform] {
+						fmt.Printf("       %-8s %s\n", platform, tmpl)
+					}
+				}
+				continue
+			}
+
+			paths, errs := r.ExpandPaths(goos)
+			for _, path := range paths {
+				fmt.Printf("       %s\n", path)
+			}
+			for _, err := range errs {
+				fmt.Printf("       %s\n", warningStyle.Render(err.Error()))
+			}
+			if len(paths) == 0 && len(errs) == 0 {
+				fmt.Printf("       %s\n", helpStyle.Render("(not used on "+goos+")"))
+			}
+		}
+		fmt.Println()
+	}
+}
+
+// levelStyleFor returns the color style for a safety level
+func levelStyleFor(level string) lipgloss.Style {
+	switch level {
+	case "caution":
+		return cautionStyle
+	case "warning":
+		return warningStyle
+	default:
+		return safeStyle
+	}
+}
+
+// levelIconFor returns the legend icon for a safety level
+func levelIconFor(level string) string {
+	switch level {
+	case "caution":
+		return "⚠"
+	case "warning":
+		return "⛔"
+	default:
+		return "✓"
+	}
+}
@@ -0,0 +1,66 @@
+// Package units parses and formats the human-friendly durations used on the
+// command line and in config files.
+package units
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	Day   = 24 * time.Hour
+	Week  = 7 * Day
+	Month = 30 * Day
+	Year  = 365 * Day
+)
+
+var suffixes = []struct {
+	suffix string
+	unit   time.Duration
+}{
+	{"mo", Month},
+	{"y", Year},
+	{"w", Week},
+	{"d", Day},
+}
+
+// ParseDuration accepts Go durations ("36h", "90m") plus whole or
+// fractional days, weeks, months and years: "30d", "2w", "6mo", "1y"
+func ParseDuration(s string) (time.Duration, error) {
+	s = strings.TrimSpace(strings.ToLower(s))
+	for _, u := range suffixes {
+		if !strings.HasSuffix(s, u.suffix) {
+			continue
+		}
+		n, err := strconv.ParseFloat(strings.TrimSuffix(s, u.suffix), 64)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		return time.Duration(n * float64(u.unit)), nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid duration %q (use e.g. 12h, 30d, 2w, 6mo, 1y)", s)
+	}
+	return d, nil
+}
+
+// FormatAge renders a duration compactly for table columns: "5h", "12d",
+// "3mo", "2y"
+func FormatAge(d time.Duration) string {
+	switch {
+	case d < time.Hour:
+		return "<1h"
+	case d < Day:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	case d < 2*Month:
+		return fmt.Sprintf("%dd", d/Day)
+	case d < Year:
+		return fmt.Sprintf("%dmo", d/Month)
+	default:
+		return fmt.Sprintf("%dy", d/Year)
+	}
+}
@@ -0,0 +1,58 @@
+package units
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Duration
+	}{
+		{"36h", 36 * time.Hour},
+		{"90m", 90 * time.Minute},
+		{"0", 0},
+		{"30d", 30 * Day},
+		{"1.5d", 36 * time.Hour},
+		{"2w", 14 * Day},
+		{"6mo", 180 * Day},
+		{"1y", 365 * Day},
+		{" 7D ", 7 * Day},
+		{"1MO", Month},
+	}
+	for _, tt := range tests {
+		got, err := ParseDuration(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseDuration(%q) = %v, %v; want %v", tt.in, got, err, tt.want)
+		}
+	}
+
+	for _, in := range []string{"", "d", "7", "-1d", "-2h", "3 days", "1m2d", "soon"} {
+		if got, err := ParseDuration(in); err == nil {
+			t.Errorf("ParseDuration(%q) = %v, want an error", in, got)
+		}
+	}
+}
+
+func TestFormatAge(t *testing.T) {
+	tests := []struct {
+		in   time.Duration
+		want string
+	}{
+		{0, "<1h"},
+		{59 * time.Minute, "<1h"},
+		{5 * time.Hour, "5h"},
+		{Day, "1d"},
+		{59 * Day, "59d"},
+		{2 * Month, "2mo"},
+		{Year - time.Hour, "12mo"},
+		{Year, "1y"},
+		{3*Year + Month, "3y"},
+	}
+	for _, tt := range tests {
+		if got := FormatAge(tt.in); got != tt.want {
+			t.Errorf("FormatAge(%v) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+// Package workspaces discovers the folders recently opened in Antigravity,
+// VS Code and Cursor, so projects are found wherever the user works rather
+// than only under the configured scan roots.
+package workspaces
+
+import (
+	"encoding/json"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+
+	"github.com/iml1s/antigravity-cleaner/internal/rules"
+)
+
+// Workspace is a recently opened folder
+type Workspace struct {
+	Path string
+	// Editors names the editors that opened it, e.g. "Cursor"
+	Editors []string
+}
+
+// editors maps an editor's name to its user data directory, which holds
+// User/globalStorage
+var editors = []struct {
+	name string
+	dir  string
+}{
+	{"Antigravity", "Antigravity"},
+	{"VS Code", "Code"},
+	{"Cursor", "Cursor"},
+}
+
+// Discover returns the existing local folders recently opened in any
+// supported editor, sorted by path. Editors that aren't installed, and state
+// files that can't be read, are skipped.
+func Discover() []Workspace {
+	byPath := make(map[string]*Workspace)
+	for _, editor := range editors {
+		storage, err := rules.Expand(userDataDir(editor.dir) + "/User/globalStorage")
+		if err != nil {
+			continue
+		}
+		for _, path := range readFolders(storage) {
+			ws, ok := byPath[path]
+			if !ok {
+				ws = &Workspace{Path: path}
+				byPath[path] = ws
+			}
+			if len(ws.Editors) == 0 || ws.Editors[len(ws.Editors)-1] != editor.name {
+				ws.Editors = append(ws.Editors, editor.name)
+			}
+		}
+	}
+
+	result := make([]Workspace, 0, len(byPath))
+	for _, ws := range byPath {
+		result = append(result, *ws)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Path < result[j].Path })
+	return result
+}
+
+// userDataDir returns the path template of an editor's user data directory
+func userDataDir(dir string) string {
+	switch runtime.GOOS {
+	case "darwin":
+		return "~/Library/Application Support/" + dir
+	case "windows":
+		return "${APPDATA}/" + dir
+	}
+	return "${XDG_CONFIG_HOME}/" + dir
+}
+
+// readFolders collects the folders named in an editor's globalStorage:
+// storage.json holds the open windows and, in older versions, the recently
+// opened list, which newer versions keep in the state.vscdb SQLite
+// database instead. The database is only scanned, so the list is
+// best-effort: see folderURIPattern.
+func readFolders(storage string) []string {
+	var uris []string
+	if data, err := os.ReadFile(filepath.Join(storage, "storage.json")); err == nil {
+		var state any
+		if json.Unmarshal(data, &state) == nil {
+			uris = append(uris, jsonFolders(state)...)
+		}
+	}
+	// Recent changes may still sit in the write-ahead log, not yet copied
+	// back into the database
+	for _, name := range []string{"state.vscdb", "state.vscdb-wal"} {
+		if data, err := os.ReadFile(filepath.Join(storage, name)); err == nil {
+			uris = append(uris, scanFolders(data)...)
+		}
+	}
+
+	var paths []string
+	for _, uri := range uris {
+		if path, ok := localPath(uri); ok && isDir(path) {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// folderKeys are the storage.json keys whose values are folder URIs
+var folderKeys = map[string]bool{"folder": true, "folderUri": true}
+
+// jsonFolders walks decoded JSON for folder URIs
+func jsonFolders(v any) []string {
+	var uris []string
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			if s, ok := value.(string); ok && folderKeys[key] {
+				uris = append(uris, s)
+				continue
+			}
+			uris = append(uris, jsonFolders(value)...)
+		}
+	case []any:
+		for _, value := range v {
+			uris = append(uris, jsonFolders(value)...)
+		}
+	}
+	return uris
+}
+
+// folderURIPattern matches folderUri entries in the JSON the editors store
+// as text inside state.vscdb. Matching the raw bytes avoids a SQLite
+// dependency but is best-effort: a long recently opened list overflows
+// onto further pages, and an entry split across a page boundary is
+// missed, while stale copies of pages may name folders since dropped from
+// the list. Discovered folders only add scan roots, so a missed one is
+// just not scanned unless a configured root covers it.
+var folderURIPattern = regexp.MustCompile(`"folderUri"\s*:\s*"(file://[^"]+)"`)
+
+func scanFolders(data []byte) []string {
+	var uris []string
+	for _, m := range folderURIPattern.FindAllSubmatch(data, -1) {
+		uris = append(uris, string(m[1]))
+	}
+	return uris
+}
+
+// localPath converts a file:// URI to a path, rejecting remote workspaces
+// such as vscode-remote:// ones
+func localPath(uri string) (string, bool) {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" || (u.Host != "" && u.Host != "localhost") {
+		return "", false
+	}
+	path := u.Path
+	// file:///c%3A/Users/me decodes to /c:/Users/me
+	if runtime.GOOS == "windows" && len(path) > 2 && path[0] == '/' && path[2] == ':' {
+		path = path[1:]
+	}
+	path = filepath.Clean(filepath.FromSlash(path))
+	if !filepath.IsAbs(path) {
+		return "", false
+	}
+	return path, true
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
@@ -0,0 +1,52 @@
+// Package xdg resolves the per-user directories agc stores its files in,
+// following the XDG Base Directory spec with platform fallbacks.
+package xdg
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// ConfigHome returns $XDG_CONFIG_HOME, defaulting to ~/.config
+// (%APPDATA% on Windows)
+func ConfigHome() string {
+	return resolve("XDG_CONFIG_HOME", "APPDATA", ".config")
+}
+
+// CacheHome returns $XDG_CACHE_HOME, defaulting to ~/.cache
+// (%LOCALAPPDATA% on Windows)
+func CacheHome() string {
+	return resolve("XDG_CACHE_HOME", "LOCALAPPDATA", ".cache")
+}
+
+// DataHome returns $XDG_DATA_HOME, defaulting to ~/.local/share
+// (%LOCALAPPDATA% on Windows)
+func DataHome() string {
+	return resolve("XDG_DATA_HOME", "LOCALAPPDATA", filepath.Join(".local", "share"))
+}
+
+// StateHome returns $XDG_STATE_HOME, defaulting to ~/.local/state
+// (%LOCALAPPDATA% on Windows)
+func StateHome() string {
+	return resolve("XDG_STATE_HOME", "LOCALAPPDATA", filepath.Join(".local", "state"))
+}
+
+// resolve returns the XDG variable when it holds an absolute path, as the
+// spec requires, and otherwise falls back to the platform default. An empty
+// string is returned when no home directory can be determined.
+func resolve(xdgVar, windowsVar, homeRelative string) string {
+	if dir := os.Getenv(xdgVar); filepath.IsAbs(dir) {
+		return dir
+	}
+	if runtime.GOOS == "windows" {
+		if dir := os.Getenv(windowsVar); dir != "" {
+			return dir
+		}
+	}
+	home, err := os.UserHomeDir()
+	if err != nil || home == "" {
+		return ""
+	}
+	return filepath.Join(home, homeRelative)
+}